@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"order_service/internal/auth"
+	"order_service/internal/config"
+	"order_service/internal/export"
+	"order_service/internal/infra/postgres"
+	"order_service/internal/models"
+	"order_service/internal/pii"
+	"order_service/internal/ports/adapters/storage"
+	"order_service/internal/service"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const exportUsage = `usage: order_service export [flags]`
+
+// runExport writes the orders matching the filters to -out, delivery details are masked
+// by the PII policy unless -with-pii is set. The summary goes to summary, not to the export.
+func runExport(cnf config.Config, args []string, stdout, summary io.Writer, masker *pii.Masker, logger *slog.Logger) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), exportUsage)
+		fs.PrintDefaults()
+	}
+	var (
+		filter  models.OrderFilter
+		format  string
+		outPath string
+		withPII bool
+	)
+	fs.StringVar(&format, "format", string(export.NDJSON), "ndjson, csv or parquet")
+	fs.StringVar(&outPath, "out", "", "file to write, stdout when empty")
+	fs.StringVar(&filter.CustomerID, "customer", "", "only orders of this customer")
+	fs.StringVar(&filter.DeliveryService, "delivery-service", "", "only orders shipped by this delivery service")
+	fs.IntVar(&filter.MinAmount, "min-amount", 0, "only orders with a payment amount of at least this")
+	fs.Func("from", "only orders created at or after this RFC 3339 time", timeFlag(&filter.CreatedFrom))
+	fs.Func("to", "only orders created before this RFC 3339 time", timeFlag(&filter.CreatedTo))
+	fs.IntVar(&filter.Limit, "limit", 0, "at most this many orders, 0 exports all of them")
+	fs.BoolVar(&withPII, "with-pii", false, "export delivery details unmasked")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("export: unexpected arguments %v", fs.Args())
+	}
+	f, err := export.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if withPII {
+		ctx = auth.WithPrincipal(ctx, auth.CLIExport)
+		logger.InfoContext(ctx, "exporting orders with unmasked pii", "subject", auth.CLIExport.Subject, "method", auth.CLIExport.Method, "out", outPath)
+	}
+
+	pool, err := postgres.New(ctx, cnf.Postgres)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	orderService := service.NewOrderService(storage.NewOrderStoragePostgres(pool, logger), nil, logger)
+
+	dst := stdout
+	var file *os.File
+	if outPath != "" {
+		file, err = os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		defer file.Close()
+		dst = file
+	}
+	out, err := export.NewWriter(f, dst)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	count := 0
+	err = orderService.ExportOrders(ctx, filter, func(o models.Order) error {
+		count++
+		return out.Write(masker.Order(ctx, o))
+	})
+	if err == nil {
+		err = out.Close()
+	}
+	//a failed close can lose buffered data, so it fails the export like a failed write
+	if err == nil && file != nil {
+		err = file.Close()
+	}
+	if err != nil {
+		if outPath != "" {
+			//a partial export looks complete for csv and ndjson, better to have nothing
+			os.Remove(outPath)
+		}
+		return fmt.Errorf("export: %w", err)
+	}
+
+	fmt.Fprintf(summary, "orders:   %d\n", count)
+	fmt.Fprintf(summary, "format:   %s\n", f)
+	fmt.Fprintf(summary, "duration: %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func timeFlag(t *time.Time) func(string) error {
+	return func(s string) error {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		*t = v
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"order_service/internal/app"
+	"order_service/internal/config"
+	"order_service/internal/infra/postgres"
+	"order_service/internal/ports/adapters/reciever"
+	"order_service/internal/ports/adapters/storage"
+	"order_service/internal/service"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const importUsage = `usage: order_service import [flags] <file.ndjson[.gz]>`
+
+// runImport saves every order of an NDJSON file through OrderService.SaveOrder,
+// exactly like orders coming from kafka
+func runImport(cnf config.Config, args []string, out io.Writer, logger *slog.Logger) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), importUsage)
+		fs.PrintDefaults()
+	}
+	var opts reciever.FileOptions
+	fs.IntVar(&opts.Concurrency, "concurrency", 4, "orders saved at once")
+	fs.StringVar(&opts.RejectsPath, "rejects", "", "NDJSON file receiving the records that failed, with the reason")
+	fs.StringVar(&opts.CheckpointPath, "checkpoint", "", "progress file, an interrupted import started again with it resumes")
+	fs.IntVar(&opts.CheckpointEvery, "checkpoint-every", 1000, "records between checkpoint writes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("import: exactly one file expected")
+	}
+
+	decodeOrder, err := app.OrderDecoder(cnf.Decode, "import")
+	if err != nil {
+		return err
+	}
+
+	//first signal stops reading, the orders in flight are finished and checkpointed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	//they get SHUTDOWN_TIMEOUT for that, or until a second signal
+	abortCtx, abort := context.WithCancel(context.Background())
+	defer abort()
+	go func() {
+		<-ctx.Done()
+		stop()
+		again, stopAgain := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stopAgain()
+		select {
+		case <-again.Done():
+		case <-time.After(cnf.HTTP.ShutdownTimeout):
+		case <-abortCtx.Done():
+		}
+		abort()
+	}()
+
+	pool, err := postgres.New(ctx, cnf.Postgres)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	//SaveOrder never reads the cache, it is filled by the first lookup of each order
+	orderService := service.NewOrderService(storage.NewOrderStoragePostgres(pool, logger), nil, logger).
+		WithValidation(cnf.Validate.Enabled)
+	fileReciever := reciever.NewRecieverFile(fs.Arg(0), decodeOrder, opts, logger)
+
+	runErr := service.NewOrderRecieverService(fileReciever, orderService.SaveOrder).WithAbort(abortCtx).Run(ctx)
+
+	s := fileReciever.Summary()
+	fmt.Fprintf(out, "lines:          %d\n", s.Lines)
+	fmt.Fprintf(out, "resumed after:  %d\n", s.Resumed)
+	fmt.Fprintf(out, "saved:          %d\n", s.Saved)
+	fmt.Fprintf(out, "duplicates:     %d\n", s.Duplicates)
+	fmt.Fprintf(out, "blank:          %d\n", s.Blank)
+	fmt.Fprintf(out, "decode failed:  %d\n", s.DecodeFailed)
+	fmt.Fprintf(out, "process failed: %d\n", s.ProcessFailed)
+	fmt.Fprintf(out, "aborted:        %d\n", s.Aborted)
+	fmt.Fprintf(out, "duration:       %s\n", s.Duration.Round(time.Millisecond))
+
+	if runErr != nil {
+		return runErr
+	}
+	if s.Failed() > 0 {
+		if opts.RejectsPath != "" {
+			return fmt.Errorf("import: %d records failed, see %s", s.Failed(), opts.RejectsPath)
+		}
+		return fmt.Errorf("import: %d records failed", s.Failed())
+	}
+	return nil
+}
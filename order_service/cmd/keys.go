@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"order_service/internal/app"
+	"order_service/internal/auth"
+	"order_service/internal/config"
+	"order_service/internal/infra/postgres"
+	"order_service/internal/models"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const keysUsage = `usage:
+  order_service keys create -name <name> -role reader|writer|admin
+  order_service keys list
+  order_service keys revoke <id>`
+
+// runKeys manages API keys in the configured key store
+func runKeys(cnf config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var pool *pgxpool.Pool
+	if cnf.Auth.KeyStore == "postgres" {
+		var err error
+		pool, err = postgres.New(ctx, cnf.Postgres)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+	}
+	store, err := app.NewKeyStore(cnf.Auth, pool)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		name := fs.String("name", "", "who or what the key is for")
+		role := fs.String("role", string(models.RoleReader), "reader, writer or admin")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("keys create: -name is required")
+		}
+		plain, key, err := auth.NewKey(*name, models.Role(*role))
+		if err != nil {
+			return err
+		}
+		if err := store.Create(ctx, key); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created key %s (%s, %s)\n", key.ID, key.Name, key.Role)
+		fmt.Fprintf(out, "%s\n", plain)
+		fmt.Fprintln(out, "the key is shown only once, store it now")
+		return nil
+
+	case "list":
+		keys, err := store.List(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tROLE\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.Revoked() {
+				revoked = k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Role, k.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		return tw.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(keysUsage)
+		}
+		if err := store.Revoke(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "revoked key %s\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown keys command %q\n%s", args[0], keysUsage)
+	}
+}
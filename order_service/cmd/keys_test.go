@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"order_service/internal/auth"
+	"order_service/internal/config"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"order_service/internal/ports/adapters/keystore"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunKeys(t *testing.T) {
+	cnf := config.Config{Auth: config.AuthConfig{KeyStore: "file", KeysFile: filepath.Join(t.TempDir(), "keys.json")}}
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		var out bytes.Buffer
+		err := runKeys(cnf, args, &out)
+		return out.String(), err
+	}
+
+	out, err := run("create", "-name", "ci", "-role", "writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], auth.KeyPrefix) {
+		t.Fatalf("create printed %q", out)
+	}
+	plain := lines[1]
+
+	//the printed key authenticates against the same store
+	a := auth.NewAuthenticator(keystore.NewKeyStoreFile(cnf.Auth.KeysFile), "", 0)
+	p, err := a.Authenticate(context.Background(), plain)
+	if err != nil || p.Role != models.RoleWriter {
+		t.Fatalf("created key: %+v, %v", p, err)
+	}
+
+	out, err = run("list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, p.Subject) || !strings.Contains(out, "ci") || strings.Contains(out, plain) {
+		t.Fatalf("list printed %q", out)
+	}
+
+	if _, err := run("revoke", p.Subject); err != nil {
+		t.Fatal(err)
+	}
+	a = auth.NewAuthenticator(keystore.NewKeyStoreFile(cnf.Auth.KeysFile), "", 0)
+	if _, err := a.Authenticate(context.Background(), plain); !errors.Is(err, errdef.ErrUnauthenticated) {
+		t.Fatalf("revoked key: err %v, want ErrUnauthenticated", err)
+	}
+	out, err = run("list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := strings.Fields(strings.Split(strings.TrimSpace(out), "\n")[1])
+	if _, err := time.Parse(time.RFC3339, row[len(row)-1]); err != nil {
+		t.Fatalf("list does not show the revocation: %q", out)
+	}
+}
+
+func TestRunKeysErrors(t *testing.T) {
+	cnf := config.Config{Auth: config.AuthConfig{KeyStore: "file", KeysFile: filepath.Join(t.TempDir(), "keys.json")}}
+	tests := []struct {
+		name string
+		cnf  config.Config
+		args []string
+		err  error
+	}{
+		{"no command", cnf, nil, nil},
+		{"unknown command", cnf, []string{"rotate"}, nil},
+		{"create without a name", cnf, []string{"create", "-role", "reader"}, nil},
+		{"create with an unknown role", cnf, []string{"create", "-name", "ci", "-role", "root"}, errdef.ErrInvalidInput},
+		{"revoke without an id", cnf, []string{"revoke"}, nil},
+		{"revoke an unknown key", cnf, []string{"revoke", "nope"}, errdef.ErrNotFound},
+		{"unknown key store", config.Config{Auth: config.AuthConfig{KeyStore: "ldap"}}, []string{"list"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runKeys(tt.cnf, tt.args, &bytes.Buffer{})
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
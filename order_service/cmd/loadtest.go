@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"order_service/internal/config"
+	"order_service/internal/loadtest"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const loadtestUsage = `usage: order_service loadtest [flags]`
+
+// runLoadtest drives GET /order/{id} of a running service and reports what it sustained.
+// Known ids come from -ids, or from GET /orders/export when no file is given.
+func runLoadtest(cnf config.Config, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), loadtestUsage)
+		fs.PrintDefaults()
+	}
+	var (
+		opts     loadtest.Options
+		dist     string
+		idsPath  string
+		pool     int
+		asJSON   bool
+		maxError float64
+	)
+	fs.StringVar(&opts.BaseURL, "url", "http://localhost"+cnf.HTTP.Addr, "base url of the service")
+	fs.StringVar(&opts.APIKey, "key", os.Getenv("ORDER_SERVICE_API_KEY"), "API key with the reader role, defaults to $ORDER_SERVICE_API_KEY")
+	fs.IntVar(&opts.Concurrency, "concurrency", 16, "requests in flight")
+	fs.DurationVar(&opts.Duration, "duration", 30*time.Second, "how long to run")
+	fs.DurationVar(&opts.Timeout, "timeout", 5*time.Second, "timeout of a single request")
+	fs.StringVar(&dist, "dist", string(loadtest.Uniform), "id distribution, uniform or zipfian")
+	fs.Float64Var(&opts.ZipfS, "zipf-s", 1.1, "zipfian exponent, > 1, higher makes fewer ids hot")
+	fs.Float64Var(&opts.Unknown, "unknown", 0, "share of requests for ids that do not exist, from 0 to 1")
+	fs.Int64Var(&opts.Seed, "seed", 1, "seed of the id picker")
+	fs.StringVar(&idsPath, "ids", "", "file with one order id per line, or NDJSON orders like the produce output")
+	fs.IntVar(&pool, "pool", 10000, "ids fetched from /orders/export when -ids is not set")
+	fs.BoolVar(&asJSON, "json", false, "print the report as JSON")
+	fs.Float64Var(&maxError, "max-error-rate", 0.01, "fail when more than this share of requests errored")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("loadtest: unexpected arguments %v", fs.Args())
+	}
+	var err error
+	if opts.Distribution, err = loadtest.ParseDistribution(dist); err != nil {
+		return fmt.Errorf("loadtest: %w", err)
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		MaxIdleConns:        opts.Concurrency,
+		MaxIdleConnsPerHost: opts.Concurrency,
+	}}
+
+	if idsPath != "" {
+		opts.IDs, err = readIDs(idsPath)
+	} else if opts.Unknown < 1 {
+		opts.IDs, err = fetchIDs(ctx, client, opts, pool)
+	}
+	if err != nil {
+		return fmt.Errorf("loadtest: %w", err)
+	}
+
+	report, err := loadtest.Run(ctx, opts, client)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else if err := printReport(out, opts, report); err != nil {
+		return err
+	}
+
+	if report.Requests > 0 && float64(report.ErrorCount()) > maxError*float64(report.Requests) {
+		return fmt.Errorf("loadtest: %d of %d requests failed", report.ErrorCount(), report.Requests)
+	}
+	return nil
+}
+
+func printReport(out io.Writer, opts loadtest.Options, r loadtest.Report) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "target:\t%s, %d workers, %s ids, %d known, %.0f%% unknown\n", opts.BaseURL, opts.Concurrency, opts.Distribution, len(opts.IDs), opts.Unknown*100)
+	fmt.Fprintf(tw, "requests:\t%d in %s\n", r.Requests, r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(tw, "throughput:\t%.1f/s\n", r.Throughput)
+	l := r.Latency
+	fmt.Fprintf(tw, "latency ms:\tmean %.2f  p50 %.2f  p90 %.2f  p95 %.2f  p99 %.2f  p99.9 %.2f  max %.2f\n", l.Mean, l.P50, l.P90, l.P95, l.P99, l.P999, l.Max)
+	if c := r.Cache; c != nil {
+		fmt.Fprintf(tw, "cache:\t%.1f%% hit ratio, %d hits  %d misses  %d errors\n", c.HitRatio*100, c.Hits, c.Misses, c.Errors)
+	}
+	for _, k := range sortedKeys(r.Status) {
+		fmt.Fprintf(tw, "status %s:\t%d\n", k, r.Status[k])
+	}
+	if len(r.Errors) == 0 {
+		fmt.Fprintf(tw, "errors:\tnone\n")
+	}
+	for _, k := range sortedKeys(r.Errors) {
+		fmt.Fprintf(tw, "error %s:\t%d\n", k, r.Errors[k])
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(tw, "warning:\t%s\n", w)
+	}
+	return tw.Flush()
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readIDs takes one id per line, lines starting with { are read as orders
+func readIDs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return scanIDs(f)
+}
+
+// fetchIDs asks the service itself for up to n existing ids
+func fetchIDs(ctx context.Context, client *http.Client, opts loadtest.Options, n int) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/orders/export?format=ndjson&limit=%d", opts.BaseURL, n), nil)
+	if err != nil {
+		return nil, err
+	}
+	if opts.APIKey != "" {
+		req.Header.Set("X-API-Key", opts.APIKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch ids: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch ids: GET /orders/export returned %s", resp.Status)
+	}
+	ids, err := scanIDs(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch ids: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("fetch ids: the service has no orders, produce some first or use -unknown 1")
+	}
+	return ids, nil
+}
+
+func scanIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "{") {
+			ids = append(ids, line)
+			continue
+		}
+		var o struct {
+			OrderUID string `json:"order_uid"`
+		}
+		//broken records of the produce output are skipped
+		if json.Unmarshal([]byte(line), &o) == nil && o.OrderUID != "" {
+			ids = append(ids, o.OrderUID)
+		}
+	}
+	return ids, sc.Err()
+}
@@ -2,68 +2,67 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"log"
-	"net/http"
+	"fmt"
+	"log/slog"
+	"order_service/internal/app"
 	"order_service/internal/config"
-	"order_service/internal/handler"
-	"order_service/internal/infra/kafka"
-	"order_service/internal/infra/postgres"
-	"order_service/internal/infra/redis"
+	"order_service/internal/logging"
 	"order_service/internal/models"
-	"order_service/internal/ports/adapters/cache"
-	"order_service/internal/ports/adapters/reciever"
-	"order_service/internal/ports/adapters/storage"
-	"order_service/internal/service"
+	"order_service/internal/pii"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-
 	cnf := config.LoadConfig()
 
-	pool, err := postgres.New(context.Background(), cnf.Postgres)
-	if err != nil {
-		panic(err)
-	}
-
-	redis, err := redis.New(cnf.Redis)
+	policy, err := pii.ParsePolicy(cnf.PII.Policy)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-
-	orderStorage := storage.NewOrderStoragePostgres(pool)
-	orderCache := cache.NewOrderCacheRedis(redis)
-
-	orderService := service.NewOrderService(orderStorage, orderCache)
-
-	orderServiceHandler := handler.NewOrderServiceHandler(orderService)
-
-	kafkaReader := kafka.NewReader(cnf.Kafka)
-	err = kafka.CreateTopicIfNotExists(cnf.Kafka)
-	if err != nil {
-		panic(err)
+	if !models.Role(cnf.PII.Role).Valid() {
+		fmt.Fprintf(os.Stderr, "unknown PII_ROLE %q, want reader, writer or admin\n", cnf.PII.Role)
+		os.Exit(1)
 	}
+	masker := pii.NewMasker(policy, models.Role(cnf.PII.Role))
 
-	kafkaReciever := reciever.NewRecieverKafka(kafkaReader, func(b []byte) (models.Order, error) {
-		var o models.Order
-		err := json.Unmarshal(b, &o)
-		return o, err
-	})
-	orderRecieverService := service.NewOrderRecieverService(kafkaReciever, orderService.SaveOrder)
+	logger := logging.New(cnf.Log, os.Stderr, masker.ReplaceAttr)
+	//anything still using the log package ends up in the same structured output
+	slog.SetDefault(logger)
 
-	go func() {
-		log.Printf("reciver is listenign on port : %s, topic:%s", cnf.Kafka.Host, cnf.Kafka.Topic)
-		err := orderRecieverService.Run(context.TODO())
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "keys":
+			err = runKeys(cnf, os.Args[2:], os.Stdout)
+		case "import":
+			err = runImport(cnf, os.Args[2:], os.Stdout, logger)
+		case "export":
+			err = runExport(cnf, os.Args[2:], os.Stdout, os.Stderr, masker, logger)
+		case "produce":
+			err = runProduce(cnf, os.Args[2:], os.Stdout, os.Stderr, logger)
+		case "loadtest":
+			err = runLoadtest(cnf, os.Args[2:], os.Stdout)
+		default:
+			err = fmt.Errorf("unknown command %q, want keys, import, export, produce or loadtest", os.Args[1])
+		}
 		if err != nil {
-			panic(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-	}()
-
-	httpHandler := orderServiceHandler.SetRoutes()
-
-	srv := http.Server{Handler: httpHandler, Addr: ":8081"}
+		return
+	}
 
-	log.Println("server is listening")
-	srv.ListenAndServe()
+	//first SIGINT/SIGTERM starts the graceful shutdown, a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
 
+	if err := app.Run(ctx, cnf, app.Deps{}, masker, logger); err != nil {
+		logger.Error("order service stopped with error", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("order service stopped")
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"order_service/internal/config"
+	"order_service/internal/generator"
+	"order_service/internal/infra/kafka"
+	"order_service/internal/logging"
+	"order_service/internal/orderschema"
+	"order_service/internal/tracing"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const produceUsage = `usage: order_service produce [flags]`
+
+// runProduce generates orders and publishes them to the orders topic, or writes them
+// as NDJSON with -out. Broken records (-corrupt) are published like the valid ones.
+func runProduce(cnf config.Config, args []string, stdout, stderr io.Writer, logger *slog.Logger) error {
+	fs := flag.NewFlagSet("produce", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), produceUsage)
+		fs.PrintDefaults()
+	}
+	var (
+		opts          generator.Options
+		count         int
+		rate          float64
+		start         string
+		outPath       string
+		schemaVersion int
+	)
+	fs.IntVar(&count, "count", 100, "orders to produce, 0 keeps going until interrupted")
+	fs.Float64Var(&rate, "rate", 0, "orders per second, 0 is as fast as possible")
+	fs.Uint64Var(&opts.Seed, "seed", 1, "seed of the generator, the same seed and -start give the same orders")
+	fs.IntVar(&opts.MinItems, "min-items", 1, "fewest items per order")
+	fs.IntVar(&opts.MaxItems, "max-items", 3, "most items per order")
+	fs.Float64Var(&opts.CorruptRate, "corrupt", 0, "share of deliberately broken records, from 0 to 1")
+	fs.StringVar(&start, "start", "", "RFC 3339 creation time of the first order, now when empty (so output is only reproducible with it set)")
+	fs.StringVar(&outPath, "out", "", "NDJSON file to write instead of publishing, - for stdout")
+	fs.IntVar(&schemaVersion, "schema-version", 0, "schema_version stamped on every record, 0 leaves it out like producers from before versioning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("produce: unexpected arguments %v", fs.Args())
+	}
+	if count < 0 || rate < 0 {
+		return errors.New("produce: count and rate must not be negative")
+	}
+	opts.Start = time.Now()
+	if start != "" {
+		var err error
+		if opts.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return fmt.Errorf("produce: start: %w", err)
+		}
+	}
+	gen, err := generator.New(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var sink produceSink
+	summary := stdout
+	switch outPath {
+	case "":
+		shutdownTracing, err := tracing.Setup(ctx, cnf.Tracing)
+		if err != nil {
+			return err
+		}
+		defer shutdownTracing(context.Background())
+		sink = &kafkaSink{w: kafka.NewWriter(cnf.Kafka, 10*time.Millisecond), topic: cnf.Kafka.Topic}
+	case "-":
+		sink = &ndjsonSink{w: bufio.NewWriter(stdout)}
+		summary = stderr
+	default:
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("produce: %w", err)
+		}
+		defer f.Close()
+		sink = &ndjsonSink{w: bufio.NewWriter(f)}
+	}
+
+	//records are sent in chunks of about 10ms worth, paced so the average rate is kept
+	chunk := 100
+	if rate > 0 {
+		chunk = max(1, int(rate/100))
+	}
+	var (
+		produced  int
+		corrupted = map[generator.Corruption]int{}
+		began     = time.Now()
+		records   = make([]produceRecord, 0, chunk)
+	)
+	for count == 0 || produced < count {
+		if rate > 0 {
+			due := began.Add(time.Duration(float64(produced) / rate * float64(time.Second)))
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Until(due)):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		records = records[:0]
+		for len(records) < chunk && (count == 0 || produced+len(records) < count) {
+			b, o, c, err := gen.Record()
+			if err != nil {
+				return fmt.Errorf("produce: %w", err)
+			}
+			if schemaVersion > 0 {
+				b = orderschema.Stamp(b, schemaVersion)
+			}
+			records = append(records, produceRecord{key: o.OrderUID, value: b})
+			if c != "" {
+				corrupted[c]++
+			}
+		}
+		if err := sink.write(ctx, records); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("produce: %w", err)
+		}
+		produced += len(records)
+	}
+	if err := sink.close(); err != nil {
+		return fmt.Errorf("produce: %w", err)
+	}
+	logger.Debug("producer finished", "orders", produced)
+
+	elapsed := time.Since(began)
+	fmt.Fprintf(summary, "produced:  %d\n", produced)
+	kinds := make([]string, 0, len(corrupted))
+	for c := range corrupted {
+		kinds = append(kinds, string(c))
+	}
+	sort.Strings(kinds)
+	for _, c := range kinds {
+		fmt.Fprintf(summary, "corrupted: %d %s\n", corrupted[generator.Corruption(c)], c)
+	}
+	fmt.Fprintf(summary, "duration:  %s\n", elapsed.Round(time.Millisecond))
+	if elapsed > 0 {
+		fmt.Fprintf(summary, "rate:      %.1f/s\n", float64(produced)/elapsed.Seconds())
+	}
+	return nil
+}
+
+type produceRecord struct {
+	key   string
+	value []byte
+}
+
+type produceSink interface {
+	write(ctx context.Context, records []produceRecord) error
+	close() error
+}
+
+type ndjsonSink struct {
+	w *bufio.Writer
+}
+
+func (s *ndjsonSink) write(_ context.Context, records []produceRecord) error {
+	for _, r := range records {
+		s.w.Write(r.value)
+		if err := s.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ndjsonSink) close() error {
+	return s.w.Flush()
+}
+
+type kafkaSink struct {
+	w     *kafkago.Writer
+	topic string
+}
+
+// write publishes one chunk, each message starts its own trace so the consumer continues it
+func (s *kafkaSink) write(ctx context.Context, records []produceRecord) error {
+	msgs := make([]kafkago.Message, len(records))
+	spans := make([]trace.Span, len(records))
+	for i, r := range records {
+		msgs[i] = kafkago.Message{
+			Key:     []byte(r.key),
+			Value:   r.value,
+			Headers: []kafkago.Header{{Key: logging.CorrelationKafkaHeader, Value: []byte(logging.NewCorrelationID())}},
+		}
+		var msgCtx context.Context
+		msgCtx, spans[i] = tracing.Start(ctx, s.topic+" publish",
+			trace.WithNewRoot(),
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(semconv.MessagingSystemKafka, semconv.MessagingDestinationName(s.topic)),
+		)
+		tracing.InjectKafka(msgCtx, &msgs[i])
+	}
+	err := s.w.WriteMessages(ctx, msgs...)
+	for _, span := range spans {
+		tracing.End(span, err)
+	}
+	return err
+}
+
+func (s *kafkaSink) close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,11 @@
+-- API keys for the HTTP and gRPC APIs, only a SHA-256 hash of the key is stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id          TEXT        PRIMARY KEY,
+    name        TEXT        NOT NULL,
+    role        TEXT        NOT NULL,
+    key_hash    TEXT        NOT NULL UNIQUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at  TIMESTAMPTZ,
+
+    CHECK (role IN ('reader', 'writer', 'admin'))
+);
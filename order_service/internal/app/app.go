@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"order_service/internal/config"
+	"order_service/internal/decoding"
+	"order_service/internal/feed"
+	"order_service/internal/grpcapi"
+	"order_service/internal/handler"
+	"order_service/internal/handler/openapi"
+	"order_service/internal/health"
+	"order_service/internal/httpcache"
+	"order_service/internal/infra/kafka"
+	"order_service/internal/metrics"
+	"order_service/internal/models"
+	"order_service/internal/orderschema"
+	"order_service/internal/pii"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/reciever"
+	"order_service/internal/service"
+	"order_service/internal/tracing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Deps replaces parts of the wiring, what is left nil is built from the config.
+// The e2e tests use it to run the service without postgres, redis and kafka.
+type Deps struct {
+	Storage ports.OrderStorage
+	Cache   ports.OrderCache
+	// Reciever replaces the kafka consumer
+	Reciever ports.OrderReciever
+	// HTTPListener and GRPCListener replace listening on the configured addresses
+	HTTPListener net.Listener
+	GRPCListener net.Listener
+}
+
+// OrderDecoder returns the decode function of the recievers for messages from source (kafka or import),
+// older schema versions are upcast and newer ones rejected, so they end up in the DLQ
+func OrderDecoder(cnf config.DecodeConfig, source string) (func([]byte) (models.Order, error), error) {
+	d, err := decoding.New(cnf.MaxMessageBytes, cnf.UnknownFields, source)
+	if err != nil {
+		return nil, err
+	}
+	return orderschema.Default(d).Decode, nil
+}
+
+// Run wires the service from cnf and deps and serves until ctx is done or a component
+// fails, then shuts everything down within cnf.HTTP.ShutdownTimeout
+func Run(ctx context.Context, cnf config.Config, deps Deps, masker *pii.Masker, logger *slog.Logger) error {
+	shutdownTracing, err := tracing.Setup(ctx, cnf.Tracing)
+	if err != nil {
+		return err
+	}
+	//runs last, so spans of the shutdown itself are flushed too
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cnf.HTTP.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	b, err := openBackends(ctx, cnf, deps, logger)
+	if err != nil {
+		return err
+	}
+
+	orderFeed := feed.NewBroker(cnf.Feed.BufferSize, cnf.Feed.ClientBuffer)
+	orderService := service.NewOrderService(b.storage, b.cache, logger).
+		WithNotifier(orderFeed).
+		WithMaxBatch(cnf.HTTP.BatchMaxSize).
+		WithValidation(cnf.Validate.Enabled)
+
+	useKafka := deps.Reciever == nil && cnf.Kafka.Enabled
+
+	//not ready until the server and the consumer are up
+	checker := health.NewChecker(cnf.Health.CacheTTL)
+	b.registerHealth(checker, cnf.Health.CheckTimeout)
+	if useKafka {
+		checker.Register("kafka", cnf.Health.CheckTimeout, kafka.GroupHealthCheck(cnf.Kafka, cnf.Health.KafkaMaxLag))
+	}
+
+	authn, err := authenticator(cnf.Auth, b.pool)
+	if err != nil {
+		b.close(logger)
+		return err
+	}
+	if authn == nil {
+		logger.Warn("authentication is disabled, every caller has full access")
+	}
+
+	limiter, err := rateLimiter(ctx, cnf.RateLimit, b.redis, logger)
+	if err != nil {
+		b.close(logger)
+		return err
+	}
+
+	cacheControl, err := httpcache.ParseCacheControl(cnf.HTTP.CacheControl)
+	if err != nil {
+		b.close(logger)
+		return err
+	}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		b.close(logger)
+		return err
+	}
+
+	bodyDecoder, err := decoding.New(cnf.Decode.MaxBodyBytes, cnf.Decode.UnknownFields, "http")
+	if err != nil {
+		b.close(logger)
+		return err
+	}
+	decodeOrder, err := OrderDecoder(cnf.Decode, "kafka")
+	if err != nil {
+		b.close(logger)
+		return err
+	}
+
+	orderServiceHandler := handler.NewOrderServiceHandler(orderService, logger).
+		WithHealth(checker).
+		WithFeed(orderFeed, cnf.Feed.Heartbeat).
+		WithAuth(authn).
+		WithPII(masker).
+		WithRateLimit(limiter).
+		WithCaching(cacheControl, cnf.HTTP.CompressionLevel).
+		WithOpenAPI(spec).
+		WithDecoder(bodyDecoder)
+
+	//listen before anything is started, a taken port is reported without a shutdown to run
+	httpListener, grpcListener := deps.HTTPListener, deps.GRPCListener
+	if httpListener == nil {
+		if httpListener, err = net.Listen("tcp", cnf.HTTP.Addr); err != nil {
+			b.close(logger)
+			return fmt.Errorf("http listen: %w", err)
+		}
+	}
+	if grpcListener == nil {
+		if grpcListener, err = net.Listen("tcp", cnf.GRPC.Addr); err != nil {
+			httpListener.Close()
+			b.close(logger)
+			return fmt.Errorf("grpc listen: %w", err)
+		}
+	}
+
+	if b.pool != nil {
+		prometheus.MustRegister(metrics.NewPoolCollector(b.pool))
+	}
+
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	defer stopConsumer()
+	//the order in flight outlives consumerCtx, the shutdown cancels it at its deadline
+	abortCtx, abortProcessing := context.WithCancel(context.Background())
+	defer abortProcessing()
+	consumerDone := make(chan error, 1)
+	var (
+		kafkaReader *kafkago.Reader
+		dlqWriter   *kafkago.Writer
+	)
+	switch {
+	case deps.Reciever != nil:
+		orderRecieverService := service.NewOrderRecieverService(deps.Reciever, orderService.SaveOrder).WithAbort(abortCtx)
+		go func() {
+			consumerDone <- orderRecieverService.Run(consumerCtx)
+		}()
+	case useKafka:
+		kafkaReader = kafka.NewReader(cnf.Kafka)
+		err = kafka.CreateTopicIfNotExists(cnf.Kafka)
+		if err != nil {
+			kafkaReader.Close()
+			httpListener.Close()
+			grpcListener.Close()
+			b.close(logger)
+			return err
+		}
+		prometheus.MustRegister(metrics.NewReaderCollector(kafkaReader))
+
+		dlqWriter = kafka.NewDLQWriter(cnf.Kafka)
+		kafkaReciever := reciever.NewRecieverKafka(kafkaReader, decodeOrder, logger).WithDLQ(dlqWriter)
+		orderRecieverService := service.NewOrderRecieverService(kafkaReciever, orderService.SaveOrder).WithAbort(abortCtx)
+		go func() {
+			logger.Info("reciever is listening", "broker", cnf.Kafka.Broker, "topic", cnf.Kafka.Topic, "group", cnf.Kafka.GroupID)
+			consumerDone <- orderRecieverService.Run(consumerCtx)
+		}()
+	default:
+		//stands in for the consumer, so the shutdown sequence stays the same
+		logger.Warn("kafka is disabled, orders only come through the APIs")
+		go func() {
+			<-consumerCtx.Done()
+			consumerDone <- nil
+		}()
+	}
+
+	httpHandler := orderServiceHandler.SetRoutes()
+
+	srv := http.Server{Handler: httpHandler}
+	//open SSE streams would otherwise keep Shutdown waiting until its deadline
+	srv.RegisterOnShutdown(orderFeed.Close)
+	serverDone := make(chan error, 1)
+	go func() {
+		logger.Info("server is listening", "addr", httpListener.Addr().String())
+		serverDone <- srv.Serve(httpListener)
+	}()
+
+	grpcServer := grpcapi.NewGRPCServer(grpcapi.NewServer(orderService, masker, logger), authn)
+	grpcDone := make(chan error, 1)
+	go func() {
+		logger.Info("grpc server is listening", "addr", grpcListener.Addr().String())
+		grpcDone <- grpcServer.Serve(grpcListener)
+	}()
+
+	checker.SetReady(true)
+
+	//wait for a signal or for one of the components to die on its own
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logger.Info("shutdown signal received")
+	case err := <-serverDone:
+		runErr = fmt.Errorf("http server: %w", err)
+		serverDone <- nil
+	case err := <-grpcDone:
+		runErr = fmt.Errorf("grpc server: %w", err)
+		grpcDone <- nil
+	case err := <-consumerDone:
+		if err != nil {
+			runErr = fmt.Errorf("kafka reciever: %w", err)
+		} else {
+			runErr = errors.New("kafka reciever stopped unexpectedly")
+		}
+		consumerDone <- nil
+	}
+
+	checker.SetReady(false)
+	if runErr == nil && cnf.Health.DrainDelay > 0 {
+		logger.Info("readiness is off, draining", "delay", cnf.Health.DrainDelay)
+		time.Sleep(cnf.Health.DrainDelay)
+	}
+
+	return errors.Join(runErr, shutdown(cnf.HTTP, components{
+		httpServer:      &srv,
+		httpDone:        serverDone,
+		grpcServer:      grpcServer,
+		grpcDone:        grpcDone,
+		stopConsumer:    stopConsumer,
+		abortProcessing: abortProcessing,
+		consumerDone:    consumerDone,
+	}, func() {
+		if kafkaReader != nil {
+			if err := kafkaReader.Close(); err != nil {
+				logger.Error("failed to close kafka reader", "error", err)
+			}
+		}
+		if dlqWriter != nil {
+			if err := dlqWriter.Close(); err != nil {
+				logger.Error("failed to close kafka DLQ writer", "error", err)
+			}
+		}
+		b.close(logger)
+	}))
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"fmt"
+	"order_service/internal/auth"
+	"order_service/internal/config"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/keystore"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewKeyStore picks the API key store from the config, pool is only used by the postgres store
+func NewKeyStore(cnf config.AuthConfig, pool *pgxpool.Pool) (ports.APIKeyStore, error) {
+	switch cnf.KeyStore {
+	case "postgres":
+		if pool == nil {
+			return nil, fmt.Errorf("AUTH_KEY_STORE=postgres needs STORAGE_BACKEND=postgres, use the file store instead")
+		}
+		return keystore.NewKeyStorePostgres(pool), nil
+	case "file":
+		return keystore.NewKeyStoreFile(cnf.KeysFile), nil
+	default:
+		return nil, fmt.Errorf("unknown AUTH_KEY_STORE %q, want postgres or file", cnf.KeyStore)
+	}
+}
+
+// authenticator builds the request authenticator, nil when authentication is disabled
+func authenticator(cnf config.AuthConfig, pool *pgxpool.Pool) (*auth.Authenticator, error) {
+	if !cnf.Enabled {
+		return nil, nil
+	}
+	store, err := NewKeyStore(cnf, pool)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewAuthenticator(store, cnf.JWTSecret, cnf.KeyCacheTTL), nil
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"order_service/internal/config"
+	"order_service/internal/health"
+	"order_service/internal/infra/postgres"
+	"order_service/internal/infra/redis"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/cache"
+	"order_service/internal/ports/adapters/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// backends are the adapters behind the storage and cache ports,
+// pool and redis are nil when the memory adapters are used instead
+type backends struct {
+	storage ports.OrderStorage
+	cache   ports.OrderCache
+	pool    *pgxpool.Pool
+	redis   *goredis.Client
+}
+
+// openBackends connects the configured storage and cache unless deps has them,
+// the memory cache drops expired orders until ctx is done
+func openBackends(ctx context.Context, cnf config.Config, deps Deps, logger *slog.Logger) (backends, error) {
+	b := backends{storage: deps.Storage, cache: deps.Cache}
+	switch {
+	case b.storage != nil:
+	case cnf.Backend.Storage == "postgres":
+		pool, err := postgres.New(ctx, cnf.Postgres)
+		if err != nil {
+			return b, err
+		}
+		b.pool = pool
+		b.storage = storage.NewOrderStoragePostgres(pool, logger)
+	case cnf.Backend.Storage == "memory":
+		logger.Warn("orders are kept in memory, they are lost on restart")
+		b.storage = storage.NewOrderStorageMemory()
+	default:
+		return b, fmt.Errorf("unknown STORAGE_BACKEND %q, want postgres or memory", cnf.Backend.Storage)
+	}
+
+	switch {
+	case b.cache != nil:
+	case cnf.Backend.Cache == "redis":
+		client, err := redis.New(cnf.Redis)
+		if err != nil {
+			b.close(logger)
+			return backends{}, err
+		}
+		b.redis = client
+		b.cache = cache.NewOrderCacheRedis(client).WithTTL(cnf.Backend.CacheTTL)
+	case cnf.Backend.Cache == "memory":
+		mem := cache.NewOrderCacheMemory(cnf.Backend.CacheTTL)
+		go mem.Cleanup(ctx, time.Minute)
+		b.cache = mem
+	default:
+		b.close(logger)
+		return backends{}, fmt.Errorf("unknown CACHE_BACKEND %q, want redis or memory", cnf.Backend.Cache)
+	}
+	return b, nil
+}
+
+// registerHealth adds a readiness check for every backend that is a separate server
+func (b backends) registerHealth(checker *health.Checker, timeout time.Duration) {
+	if b.pool != nil {
+		checker.Register("postgres", timeout, b.pool.Ping)
+	}
+	if b.redis != nil {
+		checker.Register("redis", timeout, func(ctx context.Context) error {
+			return b.redis.Ping(ctx).Err()
+		})
+	}
+}
+
+func (b backends) close(logger *slog.Logger) {
+	if b.pool != nil {
+		b.pool.Close()
+	}
+	if b.redis != nil {
+		if err := b.redis.Close(); err != nil {
+			logger.Error("failed to close redis client", "error", err)
+		}
+	}
+}
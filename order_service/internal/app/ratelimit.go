@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"order_service/internal/config"
+	"order_service/internal/ratelimit"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimiter builds the rate limiting middleware, nil when rate limiting is disabled.
+// The memory store drops idle buckets until ctx is done.
+func rateLimiter(ctx context.Context, cnf config.RateLimitConfig, client *redis.Client, logger *slog.Logger) (*ratelimit.Middleware, error) {
+	if !cnf.Enabled {
+		return nil, nil
+	}
+	routes, err := ratelimit.ParseRoutes(ratelimit.Limit{Rate: cnf.RPS, Burst: cnf.Burst}, cnf.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	var limiter ratelimit.Limiter
+	switch cnf.Store {
+	case "memory":
+		mem := ratelimit.NewMemory()
+		go mem.Cleanup(ctx, time.Minute)
+		limiter = mem
+	case "redis":
+		if client == nil {
+			return nil, fmt.Errorf("RATE_LIMIT_STORE=redis needs CACHE_BACKEND=redis")
+		}
+		limiter = ratelimit.NewRedis(client)
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q, want memory or redis", cnf.Store)
+	}
+	ipLimit := ratelimit.Limit{Rate: cnf.IPRPS, Burst: cnf.IPBurst}
+	if ipLimit.Rate > 0 && ipLimit.Burst < 1 {
+		return nil, fmt.Errorf("rate limit: RATE_LIMIT_IP_BURST must be positive, got %d", cnf.IPBurst)
+	}
+	return ratelimit.NewMiddleware(limiter, routes, cnf.TrustProxy, logger).WithIPLimit(ipLimit), nil
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"order_service/internal/config"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// components are the long-running parts of the service, each done channel
+// receives the result of the component's blocking call
+type components struct {
+	httpServer   *http.Server
+	httpDone     <-chan error
+	grpcServer   *grpc.Server
+	grpcDone     <-chan error
+	stopConsumer context.CancelFunc
+	// abortProcessing cancels the order the consumer is still processing
+	abortProcessing context.CancelFunc
+	consumerDone    <-chan error
+}
+
+// abortGrace is how long an aborted order gets to give its connection back
+const abortGrace = time.Second
+
+// shutdown stops the components in dependency order: no new http/grpc requests, drain the
+// in-flight ones, stop fetching from kafka and let the current order commit, then close
+// the kafka reader and the pools they were using. Everything shares cnf.ShutdownTimeout,
+// an order still processing at the deadline is cancelled before the pools are closed.
+func shutdown(cnf config.HTTPConfig, c components, closeResources func()) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cnf.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	//grpc drains concurrently with http, GracefulStop has no deadline of its own
+	grpcStopped := make(chan struct{})
+	go func() {
+		c.grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	if err := c.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http server shutdown: %w", err))
+	} else if err := <-c.httpDone; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+
+	select {
+	case <-grpcStopped:
+		if err := <-c.grpcDone; err != nil {
+			errs = append(errs, fmt.Errorf("grpc server: %w", err))
+		}
+	case <-ctx.Done():
+		c.grpcServer.Stop()
+		errs = append(errs, errors.New("grpc server did not drain in time"))
+	}
+
+	c.stopConsumer()
+	select {
+	case err := <-c.consumerDone:
+		if err != nil {
+			errs = append(errs, fmt.Errorf("kafka reciever: %w", err))
+		}
+	case <-ctx.Done():
+		errs = append(errs, errors.New("kafka reciever did not finish the current message in time"))
+		//closing the pools waits for every connection, the cancelled order has to give its own back first
+		c.abortProcessing()
+		select {
+		case <-c.consumerDone:
+		case <-time.After(abortGrace):
+			return errors.Join(append(errs, errors.New("kafka reciever ignored the cancellation, resources are left open"))...)
+		}
+	}
+
+	closeResources()
+
+	return errors.Join(errs...)
+}
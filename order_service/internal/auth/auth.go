@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"order_service/internal/ports"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyPrefix starts every API key, so leaked keys are easy to grep for
+const KeyPrefix = "osk_"
+
+// Principal is the authenticated caller
+type Principal struct {
+	// Subject is the key id for API keys and the sub claim for JWTs
+	Subject string
+	Role    models.Role
+	// Method is "api_key", "jwt", "cli" for the command line or "none" when authentication is disabled
+	Method string
+}
+
+// CLIExport is the caller of "order_service export -with-pii", so an unmasked export
+// can be told apart from requests let through by disabled authentication
+var CLIExport = Principal{Subject: "cli:export", Role: models.RoleAdmin, Method: "cli"}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the caller set by the middleware or interceptor
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// HashKey is what the key stores keep instead of the key itself
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewKey generates a key with a random id and secret. The plain key is shown once,
+// only the returned models.APIKey (holding the hash) is stored.
+func NewKey(name string, role models.Role) (string, models.APIKey, error) {
+	if !role.Valid() {
+		return "", models.APIKey{}, fmt.Errorf("%w: unknown role %q", errdef.ErrInvalidInput, role)
+	}
+	id, err := randomHex(6)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	plain := KeyPrefix + id + "_" + secret
+	return plain, models.APIKey{
+		ID:        id,
+		Name:      name,
+		Role:      role,
+		Hash:      HashKey(plain),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type cachedKey struct {
+	key     models.APIKey
+	expires time.Time
+}
+
+// Authenticator checks API keys against a key store and JWTs against a shared HMAC secret.
+// Either can be left out: a nil store rejects keys, an empty secret rejects JWTs.
+type Authenticator struct {
+	keys      ports.APIKeyStore
+	jwtSecret []byte
+	cacheTTL  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+// NewAuthenticator builds an authenticator, found keys are cached for cacheTTL so a
+// revoked key keeps working at most that long
+func NewAuthenticator(keys ports.APIKeyStore, jwtSecret string, cacheTTL time.Duration) *Authenticator {
+	return &Authenticator{keys: keys, jwtSecret: []byte(jwtSecret), cacheTTL: cacheTTL, cache: make(map[string]cachedKey)}
+}
+
+// Authenticate resolves a credential to its principal. Every failure matches errdef.ErrUnauthenticated.
+func (a *Authenticator) Authenticate(ctx context.Context, credential string) (Principal, error) {
+	switch {
+	case credential == "":
+		return Principal{}, fmt.Errorf("%w: no credentials", errdef.ErrUnauthenticated)
+	case strings.HasPrefix(credential, KeyPrefix):
+		return a.apiKey(ctx, credential)
+	case strings.Count(credential, ".") == 2:
+		return a.jwt(credential)
+	default:
+		return Principal{}, fmt.Errorf("%w: unrecognised credential", errdef.ErrUnauthenticated)
+	}
+}
+
+func (a *Authenticator) apiKey(ctx context.Context, plain string) (Principal, error) {
+	if a.keys == nil {
+		return Principal{}, fmt.Errorf("%w: api keys are not enabled", errdef.ErrUnauthenticated)
+	}
+	hash := HashKey(plain)
+
+	a.mu.Lock()
+	c, ok := a.cache[hash]
+	a.mu.Unlock()
+	if !ok || time.Now().After(c.expires) {
+		key, err := a.keys.GetByHash(ctx, hash)
+		if err != nil {
+			if errors.Is(err, errdef.ErrNotFound) {
+				return Principal{}, fmt.Errorf("%w: unknown api key", errdef.ErrUnauthenticated)
+			}
+			//the store being down is our problem, not the caller's
+			return Principal{}, fmt.Errorf("look up api key: %w", err)
+		}
+		c = cachedKey{key: key, expires: time.Now().Add(a.cacheTTL)}
+		a.mu.Lock()
+		a.cache[hash] = c
+		a.mu.Unlock()
+	}
+
+	if c.key.Revoked() {
+		return Principal{}, fmt.Errorf("%w: api key %s is revoked", errdef.ErrUnauthenticated, c.key.ID)
+	}
+	return Principal{Subject: c.key.ID, Role: c.key.Role, Method: "api_key"}, nil
+}
+
+type claims struct {
+	Role models.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func (a *Authenticator) jwt(token string) (Principal, error) {
+	if len(a.jwtSecret) == 0 {
+		return Principal{}, fmt.Errorf("%w: jwt is not enabled", errdef.ErrUnauthenticated)
+	}
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (any, error) {
+		return a.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", errdef.ErrUnauthenticated, err)
+	}
+	if c.Subject == "" || !c.Role.Valid() {
+		return Principal{}, fmt.Errorf("%w: jwt needs sub and a known role", errdef.ErrUnauthenticated)
+	}
+	return Principal{Subject: c.Subject, Role: c.Role, Method: "jwt"}, nil
+}
+
+// Authorize checks that the caller in ctx has at least role required
+func Authorize(ctx context.Context, required models.Role) error {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: no credentials", errdef.ErrUnauthenticated)
+	}
+	if !p.Role.Allows(required) {
+		return fmt.Errorf("%w: %s role required, caller is %s", errdef.ErrForbidden, required, p.Role)
+	}
+	return nil
+}
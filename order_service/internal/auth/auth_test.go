@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// memKeyStore holds keys by hash, err fails every lookup when set
+type memKeyStore struct {
+	keys    map[string]models.APIKey
+	err     error
+	lookups int
+}
+
+func (s *memKeyStore) GetByHash(_ context.Context, hash string) (models.APIKey, error) {
+	s.lookups++
+	if s.err != nil {
+		return models.APIKey{}, s.err
+	}
+	if k, ok := s.keys[hash]; ok {
+		return k, nil
+	}
+	return models.APIKey{}, errdef.ErrNotFound
+}
+func (s *memKeyStore) Create(_ context.Context, k models.APIKey) error {
+	s.keys[k.Hash] = k
+	return nil
+}
+func (s *memKeyStore) List(context.Context) ([]models.APIKey, error) { return nil, nil }
+func (s *memKeyStore) Revoke(_ context.Context, id string) error {
+	for hash, k := range s.keys {
+		if k.ID == id {
+			now := time.Now().UTC()
+			k.RevokedAt = &now
+			s.keys[hash] = k
+			return nil
+		}
+	}
+	return errdef.ErrNotFound
+}
+
+// newTestKey stores a new key with role and returns the plain key
+func newTestKey(t *testing.T, store *memKeyStore, role models.Role) (string, models.APIKey) {
+	t.Helper()
+	plain, key, err := NewKey(string(role), role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	return plain, key
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, c jwt.MapClaims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(method, c).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewKey(t *testing.T) {
+	plain, key, err := NewKey("ci", models.RoleWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, KeyPrefix+key.ID+"_") {
+		t.Fatalf("key %q does not start with the prefix and id %q", plain, key.ID)
+	}
+	if key.Hash != HashKey(plain) || strings.Contains(key.Hash, plain) {
+		t.Fatalf("stored hash %q is not the hash of the key", key.Hash)
+	}
+	if len(key.Hash) != 64 || HashKey(plain) != HashKey(plain) {
+		t.Fatalf("hash %q is not a stable sha256", key.Hash)
+	}
+	if key.Name != "ci" || key.Role != models.RoleWriter || key.Revoked() {
+		t.Fatalf("key %+v", key)
+	}
+
+	other, _, err := NewKey("ci", models.RoleWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == plain {
+		t.Fatal("two keys are the same")
+	}
+	if _, _, err := NewKey("ci", "root"); !errors.Is(err, errdef.ErrInvalidInput) {
+		t.Fatalf("unknown role: err %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	store := &memKeyStore{keys: map[string]models.APIKey{}}
+	writer, writerKey := newTestKey(t, store, models.RoleWriter)
+	revoked, revokedKey := newTestKey(t, store, models.RoleReader)
+	if err := store.Revoke(context.Background(), revokedKey.ID); err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	claims := func(role string, exp int64) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "svc", "role": role}
+		if exp != 0 {
+			c["exp"] = exp
+		}
+		return c
+	}
+
+	tests := []struct {
+		name       string
+		credential string
+		want       Principal
+		err        error
+	}{
+		{"api key", writer, Principal{Subject: writerKey.ID, Role: models.RoleWriter, Method: "api_key"}, nil},
+		{"unknown api key", KeyPrefix + "0000_0000", Principal{}, errdef.ErrUnauthenticated},
+		{"revoked api key", revoked, Principal{}, errdef.ErrUnauthenticated},
+		{"no credentials", "", Principal{}, errdef.ErrUnauthenticated},
+		{"unrecognised", "hunter2", Principal{}, errdef.ErrUnauthenticated},
+		{"jwt", sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims("reader", exp)), Principal{Subject: "svc", Role: models.RoleReader, Method: "jwt"}, nil},
+		{"jwt HS512", sign(t, jwt.SigningMethodHS512, []byte(testSecret), claims("admin", exp)), Principal{Subject: "svc", Role: models.RoleAdmin, Method: "jwt"}, nil},
+		{"expired jwt", sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims("reader", time.Now().Add(-time.Minute).Unix())), Principal{}, errdef.ErrUnauthenticated},
+		{"jwt without exp", sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims("reader", 0)), Principal{}, errdef.ErrUnauthenticated},
+		{"jwt with the wrong secret", sign(t, jwt.SigningMethodHS256, []byte("other"), claims("reader", exp)), Principal{}, errdef.ErrUnauthenticated},
+		{"jwt alg none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims("admin", exp)), Principal{}, errdef.ErrUnauthenticated},
+		{"jwt RS256", sign(t, jwt.SigningMethodRS256, rsaKey, claims("admin", exp)), Principal{}, errdef.ErrUnauthenticated},
+		{"jwt with an unknown role", sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims("root", exp)), Principal{}, errdef.ErrUnauthenticated},
+		{"jwt without sub", sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"role": "reader", "exp": exp}), Principal{}, errdef.ErrUnauthenticated},
+	}
+	a := NewAuthenticator(store, testSecret, time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Authenticate(context.Background(), tt.credential)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("principal %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateDisabledMethods(t *testing.T) {
+	token := sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"sub": "svc", "role": "reader", "exp": time.Now().Add(time.Hour).Unix()})
+	a := NewAuthenticator(nil, "", time.Minute)
+	for _, credential := range []string{KeyPrefix + "0000_0000", token} {
+		if _, err := a.Authenticate(context.Background(), credential); !errors.Is(err, errdef.ErrUnauthenticated) {
+			t.Fatalf("%q: err %v, want ErrUnauthenticated", credential, err)
+		}
+	}
+}
+
+// TestAuthenticateStoreFailure checks that a broken key store is a server error, not the caller's
+func TestAuthenticateStoreFailure(t *testing.T) {
+	store := &memKeyStore{keys: map[string]models.APIKey{}, err: errors.New("connection refused")}
+	_, err := NewAuthenticator(store, "", time.Minute).Authenticate(context.Background(), KeyPrefix+"0000_0000")
+	if err == nil || errors.Is(err, errdef.ErrUnauthenticated) {
+		t.Fatalf("err %v, want a lookup failure", err)
+	}
+}
+
+// TestRevokedKeyCache checks that a revoked key keeps working until its cache entry expires
+func TestRevokedKeyCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		accepted bool
+		lookups  int
+	}{
+		{"within the ttl", time.Hour, true, 1},
+		{"without a cache", 0, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memKeyStore{keys: map[string]models.APIKey{}}
+			plain, key := newTestKey(t, store, models.RoleReader)
+			a := NewAuthenticator(store, "", tt.ttl)
+			if _, err := a.Authenticate(context.Background(), plain); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Revoke(context.Background(), key.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := a.Authenticate(context.Background(), plain)
+			if accepted := err == nil; accepted != tt.accepted {
+				t.Fatalf("revoked key accepted %v, want %v: %v", accepted, tt.accepted, err)
+			}
+			if err != nil && !errors.Is(err, errdef.ErrUnauthenticated) {
+				t.Fatalf("err %v, want ErrUnauthenticated", err)
+			}
+			if store.lookups != tt.lookups {
+				t.Fatalf("%d key store lookups, want %d", store.lookups, tt.lookups)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		role     models.Role
+		required models.Role
+		err      error
+	}{
+		{models.RoleReader, models.RoleReader, nil},
+		{models.RoleReader, models.RoleWriter, errdef.ErrForbidden},
+		{models.RoleReader, models.RoleAdmin, errdef.ErrForbidden},
+		{models.RoleWriter, models.RoleReader, nil},
+		{models.RoleWriter, models.RoleAdmin, errdef.ErrForbidden},
+		{models.RoleAdmin, models.RoleWriter, nil},
+		{"root", models.RoleReader, errdef.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.required), func(t *testing.T) {
+			ctx := WithPrincipal(context.Background(), Principal{Subject: "x", Role: tt.role})
+			if err := Authorize(ctx, tt.required); !errors.Is(err, tt.err) {
+				t.Fatalf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if err := Authorize(context.Background(), models.RoleReader); !errors.Is(err, errdef.ErrUnauthenticated) {
+		t.Fatalf("no principal: err %v, want ErrUnauthenticated", err)
+	}
+}
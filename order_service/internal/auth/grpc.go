@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor authenticates grpc calls from the "authorization" (Bearer) or "x-api-key"
+// metadata and checks the role required for the method. Methods missing from roles need admin.
+// A nil authenticator lets everything through as Anonymous.
+func UnaryInterceptor(a *Authenticator, roles map[string]models.Role, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if a == nil {
+			return handler(WithPrincipal(ctx, Anonymous), req)
+		}
+		p, err := a.Authenticate(ctx, grpcCredential(ctx))
+		if err != nil {
+			if errors.Is(err, errdef.ErrUnauthenticated) {
+				return nil, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+			}
+			logger.ErrorContext(ctx, "grpc authentication failed", "method", info.FullMethod, "error", err)
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		ctx = WithPrincipal(ctx, p)
+
+		required, ok := roles[info.FullMethod]
+		if !ok {
+			required = models.RoleAdmin
+		}
+		if err := Authorize(ctx, required); err != nil {
+			return nil, status.Error(codes.PermissionDenied, "not allowed for this role")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func grpcCredential(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get("authorization"); len(v) > 0 {
+		scheme, token, ok := strings.Cut(v[0], " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if v := md.Get(strings.ToLower(APIKeyHeader)); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	store := &memKeyStore{keys: map[string]models.APIKey{}}
+	reader, readerKey := newTestKey(t, store, models.RoleReader)
+	admin, adminKey := newTestKey(t, store, models.RoleAdmin)
+	a := NewAuthenticator(store, "", time.Minute)
+	broken := NewAuthenticator(&memKeyStore{err: errors.New("connection refused")}, "", time.Minute)
+	roles := map[string]models.Role{"/orders.v1.OrderService/GetOrder": models.RoleReader}
+
+	tests := []struct {
+		name    string
+		authn   *Authenticator
+		method  string
+		md      metadata.MD
+		code    codes.Code
+		subject string
+	}{
+		{"bearer key", a, "GetOrder", metadata.Pairs("authorization", "Bearer "+reader), codes.OK, readerKey.ID},
+		{"api key metadata", a, "GetOrder", metadata.Pairs("x-api-key", reader), codes.OK, readerKey.ID},
+		{"basic auth", a, "GetOrder", metadata.Pairs("authorization", "Basic "+reader), codes.Unauthenticated, ""},
+		{"no metadata", a, "GetOrder", nil, codes.Unauthenticated, ""},
+		{"unknown key", a, "GetOrder", metadata.Pairs("x-api-key", KeyPrefix+"0000_0000"), codes.Unauthenticated, ""},
+		{"key store down", broken, "GetOrder", metadata.Pairs("x-api-key", reader), codes.Internal, ""},
+		{"method without a role needs admin", a, "SaveOrder", metadata.Pairs("x-api-key", reader), codes.PermissionDenied, ""},
+		{"admin on a method without a role", a, "SaveOrder", metadata.Pairs("x-api-key", admin), codes.OK, adminKey.ID},
+		{"disabled", nil, "SaveOrder", nil, codes.OK, Anonymous.Subject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			var seen Principal
+			handler := func(ctx context.Context, req any) (any, error) {
+				seen, _ = FromContext(ctx)
+				return req, nil
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/orders.v1.OrderService/" + tt.method}
+
+			_, err := UnaryInterceptor(tt.authn, roles, logging.Nop())(ctx, "req", info, handler)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code %s, want %s: %v", code, tt.code, err)
+			}
+			if seen.Subject != tt.subject {
+				t.Fatalf("principal %+v, want subject %q", seen, tt.subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"order_service/internal/errdef"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"strings"
+)
+
+// APIKeyHeader is an alternative to "Authorization: Bearer <key>"
+const APIKeyHeader = "X-API-Key"
+
+// Anonymous is the caller when authentication is disabled, it may do everything
+var Anonymous = Principal{Subject: "anonymous", Role: models.RoleAdmin, Method: "none"}
+
+// credential takes the bearer token, or the X-API-Key header when there is none
+func credential(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.Header.Get(APIKeyHeader)
+}
+
+// Middleware authenticates every request and puts the principal in its context.
+// A nil authenticator lets everything through as Anonymous, for local development.
+func Middleware(a *Authenticator, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if a == nil {
+				next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), Anonymous)))
+				return
+			}
+			p, err := a.Authenticate(r.Context(), credential(r))
+			if err != nil {
+				writeError(w, r, logger, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// Require rejects requests whose principal has a lower role than role, it goes after Middleware
+func Require(role models.Role, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := Authorize(r.Context(), role); err != nil {
+				writeError(w, r, logger, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error) {
+	problem := errdef.FromError(err)
+	level := slog.LevelInfo
+	if problem.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.Log(r.Context(), level, "request rejected", "status", problem.Status, "code", problem.Code, "error", err)
+
+	if errors.Is(err, errdef.ErrUnauthenticated) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="order_service"`)
+	}
+	problem.Instance = r.URL.Path
+	problem.CorrelationID = logging.CorrelationID(r.Context())
+	problem.Write(w)
+}
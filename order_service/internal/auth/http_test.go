@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/errdef"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := &memKeyStore{keys: map[string]models.APIKey{}}
+	reader, readerKey := newTestKey(t, store, models.RoleReader)
+	writer, writerKey := newTestKey(t, store, models.RoleWriter)
+	a := NewAuthenticator(store, "", time.Minute)
+
+	tests := []struct {
+		name    string
+		authn   *Authenticator
+		headers map[string]string
+		status  int
+		subject string
+	}{
+		{"bearer key", a, map[string]string{"Authorization": "Bearer " + writer}, http.StatusOK, writerKey.ID},
+		{"lowercase bearer", a, map[string]string{"Authorization": "bearer " + writer}, http.StatusOK, writerKey.ID},
+		{"api key header", a, map[string]string{APIKeyHeader: writer}, http.StatusOK, writerKey.ID},
+		{"authorization wins over the header", a, map[string]string{"Authorization": "Bearer " + writer, APIKeyHeader: reader}, http.StatusOK, writerKey.ID},
+		{"basic auth", a, map[string]string{"Authorization": "Basic " + writer}, http.StatusUnauthorized, ""},
+		{"no credentials", a, nil, http.StatusUnauthorized, ""},
+		{"reader on a writer route", a, map[string]string{APIKeyHeader: reader}, http.StatusForbidden, readerKey.ID},
+		{"disabled", nil, nil, http.StatusOK, Anonymous.Subject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen Principal
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen, _ = FromContext(r.Context())
+			})
+			h := Middleware(tt.authn, logging.Nop())(Require(models.RoleWriter, logging.Nop())(next))
+
+			req := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				if seen.Subject != tt.subject {
+					t.Fatalf("principal %+v, want subject %q", seen, tt.subject)
+				}
+				return
+			}
+			if challenge := rec.Header().Get("WWW-Authenticate"); (challenge != "") != (rec.Code == http.StatusUnauthorized) {
+				t.Fatalf("status %d with WWW-Authenticate %q", rec.Code, challenge)
+			}
+			var problem errdef.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil || problem.Instance != "/order/x" {
+				t.Fatalf("problem %+v, err %v", problem, err)
+			}
+		})
+	}
+}
@@ -4,14 +4,143 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Postgres PostgresConfig
-	Redis    RedisConfig
-	Kafka    KafkaConfig
+	HTTP      HTTPConfig
+	GRPC      GRPCConfig
+	Postgres  PostgresConfig
+	Redis     RedisConfig
+	Kafka     KafkaConfig
+	Health    HealthConfig
+	Log       LogConfig
+	Tracing   TracingConfig
+	Feed      FeedConfig
+	Auth      AuthConfig
+	PII       PIIConfig
+	RateLimit RateLimitConfig
+	Backend   BackendConfig
+	Decode    DecodeConfig
+	Validate  ValidateConfig
+}
+
+// ValidateConfig is the business validation of saved orders, on top of decoding them
+type ValidateConfig struct {
+	// Enabled rejects orders failing models.Order.Validate (missing fields, negative amounts),
+	// off by default so producers relying on partial orders keep working
+	Enabled bool
+}
+
+// DecodeConfig is how order JSON from http bodies, kafka messages and import files is decoded
+type DecodeConfig struct {
+	// MaxBodyBytes caps http request bodies, larger ones get 413
+	MaxBodyBytes int
+	// MaxMessageBytes caps kafka messages and import lines, larger ones are dead-lettered
+	MaxMessageBytes int
+	// UnknownFields is reject, or record to accept them and count them in metrics
+	UnknownFields string
+}
+
+// BackendConfig picks the adapters behind the ports, memory ones keep nothing across restarts
+type BackendConfig struct {
+	// Storage is postgres or memory
+	Storage string
+	// Cache is redis or memory
+	Cache string
+	// CacheTTL is how long an order stays cached
+	CacheTTL time.Duration
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Store is memory (per replica) or redis (shared by all replicas)
+	Store string
+	// RPS and Burst are the default token bucket of every route
+	RPS   float64
+	Burst int
+	// Routes overrides single routes: "GET /order/{id}=50:100;POST /orders:batchGet=5:10"
+	Routes string
+	// IPRPS and IPBurst cap every client IP over all routes before authentication, so
+	// failed authentications are limited too. IPRPS 0 disables it.
+	IPRPS   float64
+	IPBurst int
+	// TrustProxy takes the client IP from X-Forwarded-For
+	TrustProxy bool
+}
+
+type PIIConfig struct {
+	// Policy overrides the default masking per field, e.g. "delivery.phone=full,customer_id=partial"
+	Policy string
+	// Role is the least role that sees unmasked PII
+	Role string
+}
+
+type AuthConfig struct {
+	// Enabled false lets every request through with full access, only for local development
+	Enabled bool
+	// KeyStore is postgres or file
+	KeyStore string
+	KeysFile string
+	// JWTSecret verifies HMAC-signed JWTs, empty disables JWT authentication
+	JWTSecret string
+	// KeyCacheTTL is how long looked up keys are cached, a revoked key keeps working at most this long
+	KeyCacheTTL time.Duration
+}
+
+// FeedConfig tunes the live order feed at /orders/stream
+type FeedConfig struct {
+	// BufferSize is how many recent events are kept for Last-Event-ID resume
+	BufferSize int
+	// ClientBuffer is how many events a client may lag behind before it is dropped
+	ClientBuffer int
+	// Heartbeat is the interval of keep-alive comments on idle streams, 0 disables them
+	Heartbeat time.Duration
+}
+
+type TracingConfig struct {
+	ServiceName string
+	// Exporter is one of none, otlp, stdout, file
+	Exporter string
+	// Endpoint is the OTLP/HTTP url, empty falls back to the OTEL_EXPORTER_OTLP_* variables
+	Endpoint    string
+	FilePath    string
+	SampleRatio float64
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn, error
+	Level string
+	// Format is json or text
+	Format string
+}
+
+type HTTPConfig struct {
+	Addr string
+	// BatchMaxSize caps how many ids one batch lookup may ask for
+	BatchMaxSize int
+	// ShutdownTimeout bounds the whole graceful shutdown: draining HTTP handlers,
+	// finishing the current kafka message and closing the pools
+	ShutdownTimeout time.Duration
+	// CompressionLevel is the gzip/brotli level of responses, 0 disables compression
+	CompressionLevel int
+	// CacheControl sets Cache-Control per route: "GET /order/{id}=private, max-age=60;GET /ui/*=public, max-age=300"
+	CacheControl string
+}
+
+type GRPCConfig struct {
+	Addr string
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration
+	CacheTTL     time.Duration
+	// KafkaMaxLag makes readiness fail when the consumer group falls this far behind, 0 disables it
+	KafkaMaxLag int64
+	// DrainDelay keeps serving after readiness flips false, so load balancers stop routing first
+	DrainDelay time.Duration
 }
 
 type PostgresConfig struct {
@@ -34,6 +163,11 @@ type KafkaConfig struct {
 	GroupID string
 	Topic   string
 	Broker  string
+	// Enabled false runs without the kafka consumer, orders only come through the APIs
+	Enabled bool
+	// DLQTopic receives messages that could not be decoded or processed, empty (the default)
+	// only logs and skips them. A message is not committed until the DLQ has it.
+	DLQTopic string
 }
 
 func LoadConfig() Config {
@@ -41,8 +175,27 @@ func LoadConfig() Config {
 	if err != nil {
 		log.Println("No .env file found")
 	}
+	//DEV_MODE runs without postgres, redis and kafka unless they are asked for explicitly
+	dev := getEnvAsBool("DEV_MODE", false)
+	pick := func(prod, devVal string) string {
+		if dev {
+			return devVal
+		}
+		return prod
+	}
+
 	// Parse configuration
 	return Config{
+		HTTP: HTTPConfig{
+			Addr:             getEnv("HTTP_ADDR", ":8081"),
+			BatchMaxSize:     getEnvAsInt("BATCH_MAX_SIZE", 100),
+			ShutdownTimeout:  getEnvAsDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+			CompressionLevel: getEnvAsInt("HTTP_COMPRESSION_LEVEL", 5),
+			CacheControl:     getEnv("HTTP_CACHE_CONTROL", "GET /order/{id}=private, max-age=60;GET /ui/*=public, max-age=300"),
+		},
+		GRPC: GRPCConfig{
+			Addr: getEnv("GRPC_ADDR", ":9090"),
+		},
 		Postgres: PostgresConfig{
 			Host:     getEnv("POSTGRES_HOST", "localhost"),
 			Port:     getEnvAsInt("POSTGRES_PORT", 5432),
@@ -54,11 +207,69 @@ func LoadConfig() Config {
 			ConnString: getEnv("REDIS_CONN_STRING", "redis://localhost:6379/0"),
 		},
 		Kafka: KafkaConfig{
-			Host:    getEnv("RABBITMQ_HOST", "localhost"),
-			Port:    getEnvAsInt("RABBITMQ_PORT", 5672),
-			GroupID: getEnv("KAFKA_GROUP_ID", "group1"),
-			Topic:   getEnv("KAFKA_TOPIC", "orders"),
-			Broker:  getEnv("KAFKA_BROKER", "localhost:9092"),
+			Host:     getEnv("RABBITMQ_HOST", "localhost"),
+			Port:     getEnvAsInt("RABBITMQ_PORT", 5672),
+			GroupID:  getEnv("KAFKA_GROUP_ID", "group1"),
+			Topic:    getEnv("KAFKA_TOPIC", "orders"),
+			Broker:   getEnv("KAFKA_BROKER", "localhost:9092"),
+			Enabled:  getEnvAsBool("KAFKA_ENABLED", !dev),
+			DLQTopic: getEnv("KAFKA_DLQ_TOPIC", ""),
+		},
+		Log: LogConfig{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+		Tracing: TracingConfig{
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "order_service"),
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			Endpoint:    getEnv("TRACING_OTLP_ENDPOINT", ""),
+			FilePath:    getEnv("TRACING_FILE", "traces.json"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+		Feed: FeedConfig{
+			BufferSize:   getEnvAsInt("FEED_BUFFER_SIZE", 1000),
+			ClientBuffer: getEnvAsInt("FEED_CLIENT_BUFFER", 64),
+			Heartbeat:    getEnvAsDuration("FEED_HEARTBEAT", 15*time.Second),
+		},
+		Auth: AuthConfig{
+			Enabled:     getEnvAsBool("AUTH_ENABLED", true),
+			KeyStore:    getEnv("AUTH_KEY_STORE", pick("postgres", "file")),
+			KeysFile:    getEnv("AUTH_KEYS_FILE", "api_keys.json"),
+			JWTSecret:   getEnv("AUTH_JWT_SECRET", ""),
+			KeyCacheTTL: getEnvAsDuration("AUTH_KEY_CACHE_TTL", 30*time.Second),
+		},
+		PII: PIIConfig{
+			Policy: getEnv("PII_POLICY", ""),
+			Role:   getEnv("PII_ROLE", "admin"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:    getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Store:      getEnv("RATE_LIMIT_STORE", "memory"),
+			RPS:        getEnvAsFloat("RATE_LIMIT_RPS", 50),
+			Burst:      getEnvAsInt("RATE_LIMIT_BURST", 100),
+			Routes:     getEnv("RATE_LIMIT_ROUTES", ""),
+			IPRPS:      getEnvAsFloat("RATE_LIMIT_IP_RPS", 200),
+			IPBurst:    getEnvAsInt("RATE_LIMIT_IP_BURST", 400),
+			TrustProxy: getEnvAsBool("RATE_LIMIT_TRUST_PROXY", false),
+		},
+		Backend: BackendConfig{
+			Storage:  getEnv("STORAGE_BACKEND", pick("postgres", "memory")),
+			Cache:    getEnv("CACHE_BACKEND", pick("redis", "memory")),
+			CacheTTL: getEnvAsDuration("CACHE_TTL", time.Hour),
+		},
+		Decode: DecodeConfig{
+			MaxBodyBytes:    getEnvAsInt("HTTP_MAX_BODY_BYTES", 1<<20),
+			MaxMessageBytes: getEnvAsInt("MAX_MESSAGE_BYTES", 1<<20),
+			UnknownFields:   getEnv("DECODE_UNKNOWN_FIELDS", "record"),
+		},
+		Validate: ValidateConfig{
+			Enabled: getEnvAsBool("VALIDATE_ORDERS", false),
+		},
+		Health: HealthConfig{
+			CheckTimeout: getEnvAsDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			CacheTTL:     getEnvAsDuration("HEALTH_CACHE_TTL", 5*time.Second),
+			KafkaMaxLag:  int64(getEnvAsInt("HEALTH_KAFKA_MAX_LAG", 0)),
+			DrainDelay:   getEnvAsDuration("HEALTH_DRAIN_DELAY", 0),
 		},
 	}
 }
@@ -77,3 +288,27 @@ func getEnvAsInt(key string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getEnvAsDuration(key string, defaultVal time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvAsBool(key string, defaultVal bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvAsFloat(key string, defaultVal float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultVal
+}
@@ -0,0 +1,221 @@
+// Package decoding decodes untrusted JSON payloads: http bodies, kafka messages and
+// import lines. A payload must be a single JSON value within the size limit, unknown
+// fields are rejected or counted depending on the policy.
+package decoding
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"order_service/internal/errdef"
+	"order_service/internal/metrics"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Unknown field policies
+const (
+	// Reject fails payloads with fields the target type doesn't have
+	Reject = "reject"
+	// Record accepts them and counts them in order_service_decode_unknown_fields_total
+	Record = "record"
+)
+
+// Decoder decodes JSON under a size limit and an unknown field policy, the zero value
+// has no limit and ignores unknown fields without counting them
+type Decoder struct {
+	maxBytes int
+	strict   bool
+	source   string
+}
+
+// New returns a decoder for payloads from source (http, kafka or import), maxBytes <= 0 disables the limit
+func New(maxBytes int, unknownFields, source string) (Decoder, error) {
+	d := Decoder{maxBytes: maxBytes, source: source}
+	switch unknownFields {
+	case Reject:
+		d.strict = true
+	case Record:
+	default:
+		return Decoder{}, fmt.Errorf("unknown field policy %q, want %s or %s", unknownFields, Reject, Record)
+	}
+	return d, nil
+}
+
+// MaxBytes is the size limit, 0 when there is none
+func (d Decoder) MaxBytes() int {
+	return d.maxBytes
+}
+
+// CheckSize returns an error wrapping errdef.ErrTooLarge when n bytes are over the limit
+func (d Decoder) CheckSize(n int) error {
+	if d.maxBytes > 0 && n > d.maxBytes {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", errdef.ErrTooLarge, n, d.maxBytes)
+	}
+	return nil
+}
+
+// Decode decodes b into v. Errors wrap errdef.ErrTooLarge or errdef.ErrInvalidInput and
+// only describe the payload, so they are safe to show to its sender.
+func (d Decoder) Decode(b []byte, v any) error {
+	if err := d.CheckSize(len(b)); err != nil {
+		return err
+	}
+	if err := decodeOne(b, v, d.strict); err != nil {
+		return fmt.Errorf("%w: %w", errdef.ErrInvalidInput, err)
+	}
+	if !d.strict && d.source != "" {
+		//DisallowUnknownFields decodes the whole payload before failing, so a strict pass
+		//would allocate it twice, walking the tokens costs a fraction of that
+		if paths, err := unknownFields(json.NewDecoder(bytes.NewReader(b)), reflect.TypeOf(v), ""); err == nil {
+			//counted once per payload, not once per item carrying the field
+			slices.Sort(paths)
+			for _, path := range slices.Compact(paths) {
+				metrics.UnknownField(d.source, path)
+			}
+		}
+	}
+	return nil
+}
+
+// DecodeReader reads r like Read and decodes it into v like Decode
+func (d Decoder) DecodeReader(r io.Reader, v any) error {
+	b, err := d.Read(r)
+	if err != nil {
+		return err
+	}
+	return d.Decode(b, v)
+}
+
+// Read reads r up to one byte over the size limit, so decoding the result reports a payload
+// that is too large. r may be an http.MaxBytesReader, hitting its limit is reported as
+// ErrTooLarge too.
+func (d Decoder) Read(r io.Reader) ([]byte, error) {
+	if d.maxBytes > 0 {
+		//one byte over is enough to tell the payload is too large
+		r = io.LimitReader(r, int64(d.maxBytes)+1)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("%w: the limit is %d bytes", errdef.ErrTooLarge, tooLarge.Limit)
+		}
+		return nil, fmt.Errorf("%w: read: %w", errdef.ErrInvalidInput, err)
+	}
+	return b, nil
+}
+
+// Func adapts d to the decode functions the recievers take
+func Func[T any](d Decoder) func([]byte) (T, error) {
+	return func(b []byte) (T, error) {
+		var v T
+		err := d.Decode(b, &v)
+		return v, err
+	}
+}
+
+var errTrailingData = errors.New("unexpected data after the JSON value")
+
+func decodeOne(b []byte, v any, disallowUnknown bool) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// unknownFields reads the next value from dec and lists the object keys that t has no
+// field for, as dotted paths without array indexes (items.color). Keys match fields
+// case-insensitively like encoding/json. The value is walked token by token instead of
+// unmarshalled into maps, so counting costs little next to decoding the payload itself.
+// A nil t only skips the value.
+func unknownFields(dec *json.Decoder, t reflect.Type, prefix string) ([]string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil, nil
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (reflect.PointerTo(t).Implements(jsonUnmarshaler) || reflect.PointerTo(t).Implements(textUnmarshaler)) {
+		t = nil
+	}
+
+	var paths []string
+	for dec.More() {
+		next, path := reflect.Type(nil), prefix
+		if delim == '{' {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := tok.(string)
+			switch {
+			case t == nil:
+			case t.Kind() == reflect.Struct:
+				if name, field, ok := fieldByJSONName(t, key); ok {
+					next, path = field.Type, prefix+name+"."
+				} else {
+					paths = append(paths, prefix+key)
+				}
+			case t.Kind() == reflect.Map:
+				//map keys are data, not fields, they don't become part of the path
+				next, path = t.Elem(), prefix+"*."
+			}
+		} else if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			next = t.Elem()
+		}
+		found, err := unknownFields(dec, next, path)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, found...)
+	}
+	//the closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// fieldByJSONName returns the field key decodes into with its canonical json name
+func fieldByJSONName(t reflect.Type, key string) (string, reflect.StructField, bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, key) {
+			return name, f, true
+		}
+	}
+	return "", reflect.StructField{}, false
+}
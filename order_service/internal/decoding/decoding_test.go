@@ -0,0 +1,134 @@
+package decoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const order = `{"order_uid": "b563feb7b2b84b6test", "delivery": {"name": "Test Testov"}, "items": [{"chrt_id": 1}, {"chrt_id": 2}]}`
+
+func TestDecode(t *testing.T) {
+	strict, err := New(256, Reject, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lenient, err := New(256, Record, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		// strictErr and lenientErr are the errors wrapped, nil when the payload is accepted
+		strictErr, lenientErr error
+	}{
+		{"known fields", order, nil, nil},
+		{"surrounding whitespace", " \n" + order + "\n ", nil, nil},
+		{"case-insensitive match", `{"ORDER_UID": "x"}`, nil, nil},
+		{"unknown top-level field", `{"order_uid": "x", "colour": "red"}`, errdef.ErrInvalidInput, nil},
+		{"misspelled nested field", `{"delivery": {"nmae": "x"}}`, errdef.ErrInvalidInput, nil},
+		{"unknown item field", `{"items": [{"chrt_id": 1, "discount": 5}]}`, errdef.ErrInvalidInput, nil},
+		{"trailing object", order + `{}`, errdef.ErrInvalidInput, errdef.ErrInvalidInput},
+		{"trailing garbage", order + ` garbage`, errdef.ErrInvalidInput, errdef.ErrInvalidInput},
+		{"unknown field and trailing data", `{"colour": "red"} x`, errdef.ErrInvalidInput, errdef.ErrInvalidInput},
+		{"truncated", order[:len(order)/2], errdef.ErrInvalidInput, errdef.ErrInvalidInput},
+		{"empty", ``, errdef.ErrInvalidInput, errdef.ErrInvalidInput},
+		{"too large", `{"order_uid": "` + strings.Repeat("a", 256) + `"}`, errdef.ErrTooLarge, errdef.ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []struct {
+				d    Decoder
+				want error
+			}{{strict, tt.strictErr}, {lenient, tt.lenientErr}} {
+				var o models.Order
+				err := c.d.Decode([]byte(tt.payload), &o)
+				if c.want == nil && err != nil {
+					t.Fatalf("strict %v: %v", c.d.strict, err)
+				}
+				if c.want != nil && !errors.Is(err, c.want) {
+					t.Fatalf("strict %v: got %v, want %v", c.d.strict, err, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLenientDecodeKeepsKnownFields(t *testing.T) {
+	d, err := New(0, Record, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var o models.Order
+	if err := d.Decode([]byte(`{"colour": "red", "order_uid": "x", "items": [{"discount": 5, "chrt_id": 7}]}`), &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.OrderUID != "x" || len(o.Items) != 1 || o.Items[0].ChrtID != 7 {
+		t.Fatalf("got %+v", o)
+	}
+}
+
+func TestUnknownFieldPaths(t *testing.T) {
+	raw := map[string]any{
+		"order_uid": "x",
+		"colour":    "red",
+		"Delivery":  map[string]any{"name": "x", "nmae": "x"},
+		"items":     []any{map[string]any{"chrt_id": 1.0, "discount": 5.0}, map[string]any{"discount": 1.0}},
+		//time.Time decodes itself, its value is not walked
+		"date_created": map[string]any{"nested": true},
+		"payment":      "not an object",
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unknownFields(json.NewDecoder(bytes.NewReader(b)), reflect.TypeFor[*models.Order](), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	want := []string{"colour", "delivery.nmae", "items.discount", "items.discount"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecodeReaderMaxBytesReader(t *testing.T) {
+	d, err := New(0, Reject, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader(order)), 16)
+	var o models.Order
+	if err := d.DecodeReader(body, &o); !errors.Is(err, errdef.ErrTooLarge) {
+		t.Fatalf("got %v, want ErrTooLarge", err)
+	}
+
+	limited, err := New(16, Reject, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := limited.DecodeReader(strings.NewReader(order), &o); !errors.Is(err, errdef.ErrTooLarge) {
+		t.Fatalf("got %v, want ErrTooLarge", err)
+	}
+	if err := limited.DecodeReader(strings.NewReader(`{"sm_id": 1}`), &o); err != nil || o.SmID != 1 {
+		t.Fatalf("a body within the limit: %v, %+v", err, o)
+	}
+}
+
+func TestNewRejectsUnknownPolicy(t *testing.T) {
+	if _, err := New(0, "ignore", "http"); err == nil {
+		t.Fatal("want an error")
+	}
+}
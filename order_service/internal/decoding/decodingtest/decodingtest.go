@@ -0,0 +1,92 @@
+// Package decodingtest has what the fuzz tests of untrusted order payloads share: the
+// seed corpus, the allocation bound and the round-trip check of accepted orders.
+package decodingtest
+
+import (
+	"order_service/internal/generator"
+	"order_service/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// itemSize is what the costliest payload, `{},` repeated, may take per 3 bytes: each is an
+// Item, in a slice encoding/json grows by a quarter at a time (up to 6.25 times its
+// length allocated along the way), then copied into the storage and the cache encoding
+const itemSize = 9 * uint64(unsafe.Sizeof(models.Item{}))
+
+// AllocBound is the most accepting or rejecting an n byte payload may allocate when
+// payloads are capped at maxBytes (0 for no cap). A payload over the cap is read up to
+// it and not decoded at all.
+func AllocBound(n, maxBytes int) uint64 {
+	const overhead = 128 << 10
+	if maxBytes > 0 && n > maxBytes {
+		return 16*uint64(maxBytes+1) + overhead
+	}
+	return itemSize*uint64(n)/3 + overhead
+}
+
+// AddSeeds adds generated orders, each corruption kind and the pathological payloads
+func AddSeeds(f *testing.F) {
+	gen, err := generator.New(generator.Options{Seed: 1, MinItems: 1, MaxItems: 3, CorruptRate: 0.5, Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		f.Fatal(err)
+	}
+	for range 20 {
+		b, _, _, err := gen.Record()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+
+	valid, err := gen.Order().MarshalBinary()
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, s := range []string{
+		``, `null`, `[]`, `"order"`, `{}`, `{"items": null}`, `{"items": [null]}`,
+		`{"sm_id": 1e400}`, `{"sm_id": -0}`, `{"payment": {"amount": 9223372036854775808}}`,
+		`{"date_created": "0000-01-01T00:00:00Z"}`, `{"date_created": "2026-01-01T00:00:00+23:59"}`,
+		`{"order_uid": "\ud800"}`, "{\"order_uid\": \"\xff\"}", `{"ORDER_UID": "case"}`,
+		//deeper than encoding/json accepts
+		`{"x": ` + strings.Repeat("[", 20000) + strings.Repeat("]", 20000) + `}`,
+		`{"items": ` + strings.Repeat("[", 20000) + strings.Repeat("]", 20000) + `}`,
+		//huge, but within what a broker delivers
+		`{"order_uid": "` + strings.Repeat("a", 4<<20) + `"}`,
+		`{"items": [` + strings.Repeat("{},", 100000) + `{}]}`,
+		//an unknown field first, then items to decode
+		`{"x": 0, "items": [` + strings.Repeat("{},", 100000) + `{}]}`,
+		string(valid) + `{}`,
+		string(valid) + ` trailing`,
+		string(valid) + string(valid),
+		string(valid[:len(valid)-1]) + `, "unknown": {"a": [1, 2, 3]}}`,
+	} {
+		f.Add([]byte(s))
+	}
+}
+
+// AssertRoundTrip checks that o survives MarshalBinary and UnmarshalBinary unchanged,
+// DateCreated is compared as an instant with its offset
+func AssertRoundTrip(t *testing.T, o models.Order) {
+	t.Helper()
+	b, err := o.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal an accepted order: %v", err)
+	}
+	var got models.Order
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("unmarshal %s: %v", b, err)
+	}
+	_, wantOffset := o.DateCreated.Zone()
+	_, gotOffset := got.DateCreated.Zone()
+	if !got.DateCreated.Equal(o.DateCreated) || gotOffset != wantOffset {
+		t.Fatalf("date_created: got %s, want %s", got.DateCreated.Format(time.RFC3339Nano), o.DateCreated.Format(time.RFC3339Nano))
+	}
+	got.DateCreated, o.DateCreated = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(got, o) {
+		t.Fatalf("round trip changed the order:\n got %+v\nwant %+v", got, o)
+	}
+}
@@ -0,0 +1,57 @@
+package decoding
+
+import (
+	"bytes"
+	"errors"
+	"order_service/internal/decoding/decodingtest"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"runtime"
+	"testing"
+)
+
+// FuzzDecodeOrder feeds the kafka decode and validate steps untrusted bytes under both
+// policies: they must not panic, allocate more than decodingtest.AllocBound, and whatever
+// is accepted must round-trip through the cache encoding
+func FuzzDecodeOrder(f *testing.F) {
+	decodingtest.AddSeeds(f)
+	strict, err := New(8<<20, Reject, "")
+	if err != nil {
+		f.Fatal(err)
+	}
+	lenient, err := New(8<<20, Record, "fuzz")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, d := range []Decoder{strict, lenient} {
+			payload := bytes.Clone(data)
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			o, decodeErr := Func[models.Order](d)(payload)
+			var validateErr error
+			if decodeErr == nil {
+				validateErr = o.Validate()
+			}
+			runtime.ReadMemStats(&after)
+
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > decodingtest.AllocBound(len(data), d.MaxBytes()) {
+				t.Fatalf("decoding %d bytes allocated %d bytes", len(data), alloc)
+			}
+			if decodeErr != nil {
+				if !errors.Is(decodeErr, errdef.ErrInvalidInput) && !errors.Is(decodeErr, errdef.ErrTooLarge) {
+					t.Fatalf("decode error is neither invalid input nor too large: %v", decodeErr)
+				}
+				continue
+			}
+			if validateErr != nil {
+				var v *errdef.ValidationError
+				if !errors.As(validateErr, &v) || len(v.Fields) == 0 {
+					t.Fatalf("validation failed without naming a field: %v", validateErr)
+				}
+				continue
+			}
+			decodingtest.AssertRoundTrip(t, o)
+		}
+	})
+}
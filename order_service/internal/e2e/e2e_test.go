@@ -0,0 +1,225 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"order_service/internal/config"
+	"order_service/internal/e2e"
+	"order_service/internal/generator"
+	"order_service/internal/metrics"
+	"order_service/internal/models"
+	"order_service/internal/orderschema"
+	"order_service/internal/ports/adapters/storage"
+	"order_service/internal/ports/storagetest"
+	"os"
+	"testing"
+	"time"
+)
+
+// start boots the service for one test, against TEST_POSTGRES_URL when it is set
+func start(t *testing.T) (*e2e.Harness, *generator.Generator) {
+	t.Helper()
+	return startWith(t, nil)
+}
+
+// startWith is start with the config changed by configure
+func startWith(t *testing.T, configure func(*config.Config)) (*e2e.Harness, *generator.Generator) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	h, err := e2e.Start(ctx, e2e.Options{PostgresURL: os.Getenv("TEST_POSTGRES_URL"), Config: configure})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := h.Stop(); err != nil {
+			t.Errorf("service stopped with error: %v", err)
+		}
+	})
+	gen, err := generator.New(generator.Options{Seed: uint64(time.Now().UnixNano()), MinItems: 1, MaxItems: 3, Start: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, gen
+}
+
+func waitCtx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestPublishedOrdersBecomeReadable(t *testing.T) {
+	h, gen := start(t)
+	ctx := waitCtx(t)
+
+	orders := make([]models.Order, 20)
+	for i := range orders {
+		orders[i] = gen.Order()
+		if _, err := h.Publish(ctx, orders[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range orders {
+		got, err := h.WaitOrder(ctx, want.OrderUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		storagetest.AssertOrder(t, got, storagetest.Stored(want))
+	}
+	if dead := h.Broker.DeadLetters(); len(dead) != 0 {
+		t.Fatalf("valid orders were dead-lettered: %+v", dead)
+	}
+}
+
+func TestUnknownOrderIsNotFound(t *testing.T) {
+	h, gen := start(t)
+
+	_, status, err := h.GetOrder(waitCtx(t), gen.Order().OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNotFound {
+		t.Fatalf("status %d, want 404", status)
+	}
+}
+
+func TestUndecodablePayloadIsDeadLettered(t *testing.T) {
+	h, gen := start(t)
+	ctx := waitCtx(t)
+
+	offset, err := h.Broker.Publish(ctx, "broken", []byte(`{"order_uid": `))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead, err := h.WaitDeadLetter(ctx, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead.Reason != metrics.KafkaDecodeFailed {
+		t.Fatalf("reason %q, want %q", dead.Reason, metrics.KafkaDecodeFailed)
+	}
+
+	//the consumer moves on to the next message
+	o := gen.Order()
+	if _, err := h.Publish(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.WaitOrder(ctx, o.OrderUID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchemaVersions(t *testing.T) {
+	h, gen := start(t)
+	ctx := waitCtx(t)
+
+	stamp := func(o models.Order, version int) []byte {
+		b, err := json.Marshal(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return orderschema.Stamp(b, version)
+	}
+
+	current := gen.Order()
+	if _, err := h.Broker.Publish(ctx, current.OrderUID, stamp(current, orderschema.Current)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.WaitOrder(ctx, current.OrderUID); err != nil {
+		t.Fatal(err)
+	}
+
+	future := gen.Order()
+	offset, err := h.Broker.Publish(ctx, future.OrderUID, stamp(future, orderschema.Current+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead, err := h.WaitDeadLetter(ctx, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead.Reason != metrics.KafkaDecodeFailed {
+		t.Fatalf("reason %q, want %q", dead.Reason, metrics.KafkaDecodeFailed)
+	}
+	if _, status, err := h.GetOrder(ctx, future.OrderUID); err != nil || status != http.StatusNotFound {
+		t.Fatalf("order of a future version: status %d, err %v, want 404", status, err)
+	}
+}
+
+func TestInvalidOrderIsDeadLettered(t *testing.T) {
+	h, gen := startWith(t, func(cnf *config.Config) { cnf.Validate.Enabled = true })
+	ctx := waitCtx(t)
+
+	o := gen.Order()
+	o.Items = nil
+	offset, err := h.Publish(ctx, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead, err := h.WaitDeadLetter(ctx, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead.Reason != metrics.KafkaProcessFailed {
+		t.Fatalf("reason %q, want %q", dead.Reason, metrics.KafkaProcessFailed)
+	}
+	if _, status, err := h.GetOrder(ctx, o.OrderUID); err != nil || status != http.StatusNotFound {
+		t.Fatalf("invalid order: status %d, err %v, want 404", status, err)
+	}
+}
+
+func TestStorageOutage(t *testing.T) {
+	h, gen := start(t)
+	if h.Memory == nil {
+		t.Skip("faults can only be injected into the memory storage")
+	}
+	ctx := waitCtx(t)
+
+	cached := gen.Order()
+	if _, err := h.Publish(ctx, cached); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.WaitOrder(ctx, cached.OrderUID); err != nil {
+		t.Fatal(err)
+	}
+
+	outage := errors.New("connection refused")
+	h.Memory.SetFault(storage.FailOn(outage))
+
+	//orders already read are served from the cache
+	got, status, err := h.GetOrder(ctx, cached.OrderUID)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("cached order during the outage: status %d, err %v", status, err)
+	}
+	storagetest.AssertOrder(t, got, storagetest.Stored(cached))
+
+	lost := gen.Order()
+	offset, err := h.Publish(ctx, lost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead, err := h.WaitDeadLetter(ctx, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead.Reason != metrics.KafkaProcessFailed || !errors.Is(dead.Err, outage) {
+		t.Fatalf("dead letter %q with %v, want %q with the outage", dead.Reason, dead.Err, metrics.KafkaProcessFailed)
+	}
+	if _, status, err := h.GetOrder(ctx, lost.OrderUID); err != nil || status != http.StatusInternalServerError {
+		t.Fatalf("uncached order during the outage: status %d, err %v, want 500", status, err)
+	}
+
+	//replaying the dead letter after the outage stores the order
+	h.Memory.SetFault(nil)
+	if _, err := h.Broker.Publish(ctx, dead.Message.Key, dead.Message.Value); err != nil {
+		t.Fatal(err)
+	}
+	got, err = h.WaitOrder(ctx, lost.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storagetest.AssertOrder(t, got, storagetest.Stored(lost))
+}
@@ -0,0 +1,229 @@
+// Package e2e boots the whole service like cmd/main.go does, with kafka replaced by
+// an in-memory broker and postgres and redis by the memory adapters. Orders are
+// published to the broker and read back through the HTTP API.
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"order_service/internal/app"
+	"order_service/internal/config"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"order_service/internal/pii"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/cache"
+	"order_service/internal/ports/adapters/reciever"
+	"order_service/internal/ports/adapters/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Options configures the harness, the zero value runs without any external server
+type Options struct {
+	// PostgresURL stores orders in a migrated database instead of memory
+	PostgresURL string
+	// Config is applied to the loaded config before the service starts
+	Config func(*config.Config)
+}
+
+// Harness is a running service
+type Harness struct {
+	// URL is the base URL of the HTTP API
+	URL      string
+	GRPCAddr string
+	Broker   *reciever.ReceiverMemory[models.Order]
+	Storage  ports.OrderStorage
+	// Memory is the storage when no PostgresURL was given, faults can be injected through it
+	Memory *storage.OrderStorageMemory
+
+	client *http.Client
+	cancel context.CancelFunc
+	done   chan error
+	pool   *pgxpool.Pool
+}
+
+// Start boots the service and waits until /readyz reports ready
+func Start(ctx context.Context, opts Options) (*Harness, error) {
+	cnf := config.LoadConfig()
+	cnf.Auth.Enabled = false
+	cnf.RateLimit.Enabled = false
+	cnf.Tracing.Exporter = "none"
+	cnf.Health.DrainDelay = 0
+	cnf.HTTP.ShutdownTimeout = 5 * time.Second
+	if opts.Config != nil {
+		opts.Config(&cnf)
+	}
+
+	h := &Harness{client: &http.Client{Timeout: 5 * time.Second}, done: make(chan error, 1)}
+	if opts.PostgresURL != "" {
+		pool, err := pgxpool.New(ctx, opts.PostgresURL)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: %w", err)
+		}
+		if err := pool.Ping(ctx); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("postgres: %w", err)
+		}
+		h.pool = pool
+		h.Storage = storage.NewOrderStoragePostgres(pool, logging.Nop())
+	} else {
+		h.Memory = storage.NewOrderStorageMemory()
+		h.Storage = h.Memory
+	}
+	decodeOrder, err := app.OrderDecoder(cnf.Decode, "kafka")
+	if err != nil {
+		return nil, err
+	}
+	h.Broker = reciever.NewRecieverMemory(decodeOrder, 100, logging.Nop())
+
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		h.closePool()
+		return nil, err
+	}
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		httpListener.Close()
+		h.closePool()
+		return nil, err
+	}
+	h.URL = "http://" + httpListener.Addr().String()
+	h.GRPCAddr = grpcListener.Addr().String()
+
+	deps := app.Deps{
+		Storage:      h.Storage,
+		Cache:        cache.NewOrderCacheMemory(cnf.Backend.CacheTTL),
+		Reciever:     h.Broker,
+		HTTPListener: httpListener,
+		GRPCListener: grpcListener,
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	masker := pii.NewMasker(pii.DefaultPolicy(), models.RoleAdmin)
+	go func() {
+		h.done <- app.Run(runCtx, cnf, deps, masker, logging.Nop())
+	}()
+
+	if err := h.waitReady(ctx); err != nil {
+		//Run may have failed on its own, its error explains more than the timeout
+		h.cancel()
+		runErr := <-h.done
+		h.closePool()
+		return nil, errors.Join(err, runErr)
+	}
+	return h, nil
+}
+
+func (h *Harness) waitReady(ctx context.Context) error {
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL+"/readyz", nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := h.client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case err := <-h.done:
+			h.done <- err
+			return fmt.Errorf("service stopped before it was ready: %w", err)
+		case <-ctx.Done():
+			return fmt.Errorf("service is not ready: %w", ctx.Err())
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+// Stop shuts the service down and returns the error of app.Run
+func (h *Harness) Stop() error {
+	//a dialed but unused keep-alive connection holds up the http shutdown for 5s
+	h.client.CloseIdleConnections()
+	h.cancel()
+	err := <-h.done
+	h.closePool()
+	return err
+}
+
+func (h *Harness) closePool() {
+	if h.pool != nil {
+		h.pool.Close()
+	}
+}
+
+// Publish sends o to the broker like a kafka producer would, keyed by order_uid
+func (h *Harness) Publish(ctx context.Context, o models.Order) (int64, error) {
+	value, err := json.Marshal(o)
+	if err != nil {
+		return 0, err
+	}
+	return h.Broker.Publish(ctx, o.OrderUID, value)
+}
+
+// GetOrder calls GET /order/{id} and returns the status code with the decoded order on 200
+func (h *Harness) GetOrder(ctx context.Context, id string) (models.Order, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL+"/order/"+url.PathEscape(id), nil)
+	if err != nil {
+		return models.Order{}, 0, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return models.Order{}, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return models.Order{}, resp.StatusCode, nil
+	}
+	var o models.Order
+	if err := json.NewDecoder(resp.Body).Decode(&o); err != nil {
+		return models.Order{}, resp.StatusCode, fmt.Errorf("decode order: %w", err)
+	}
+	return o, resp.StatusCode, nil
+}
+
+// WaitOrder polls GET /order/{id} until the order is readable or ctx is done
+func (h *Harness) WaitOrder(ctx context.Context, id string) (models.Order, error) {
+	for {
+		o, status, err := h.GetOrder(ctx, id)
+		switch {
+		case err != nil:
+			return models.Order{}, err
+		case status == http.StatusOK:
+			return o, nil
+		case status != http.StatusNotFound:
+			return models.Order{}, fmt.Errorf("GET /order/%s: status %d", id, status)
+		}
+		select {
+		case <-ctx.Done():
+			return models.Order{}, fmt.Errorf("order %s is not readable: %w", id, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// WaitDeadLetter waits until the message at offset is dead-lettered
+func (h *Harness) WaitDeadLetter(ctx context.Context, offset int64) (reciever.DeadLetter, error) {
+	for {
+		for _, d := range h.Broker.DeadLetters() {
+			if d.Message.Offset == offset {
+				return d, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return reciever.DeadLetter{}, fmt.Errorf("message %d was not dead-lettered: %w", offset, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
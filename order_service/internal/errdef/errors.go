@@ -1 +1,154 @@
 package errdef
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Code is a stable machine-readable error identifier, clients may switch on it
+type Code string
+
+const (
+	CodeNotFound         Code = "order_not_found"
+	CodeAlreadyExists    Code = "order_already_exists"
+	CodeValidationFailed Code = "validation_failed"
+	CodeInvalidInput     Code = "invalid_input"
+	CodeRouteNotFound    Code = "route_not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeInternal         Code = "internal_error"
+	CodeUnauthenticated  Code = "unauthenticated"
+	CodeForbidden        Code = "forbidden"
+	CodeRateLimited      Code = "rate_limited"
+	CodeTooLarge         Code = "payload_too_large"
+)
+
+// Domain errors, adapters wrap them so the transport layers can map any adapter's error
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrValidation    = errors.New("validation failed")
+	ErrInvalidInput  = errors.New("invalid input")
+	//the caller has no valid credentials, or their role is too low for the operation
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+	ErrRateLimited     = errors.New("rate limited")
+	//the payload is over the configured size limit
+	ErrTooLarge = errors.New("payload too large")
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// MaxFieldErrors bounds ValidationError.Fields, so a payload with thousands of broken
+// items can't be turned into an even larger error response
+const MaxFieldErrors = 50
+
+// ValidationError lists the invalid fields, it matches ErrValidation with errors.Is
+type ValidationError struct {
+	Fields []FieldError
+	// Omitted counts the fields added after MaxFieldErrors was reached
+	Omitted int
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields)+1)
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	if e.Omitted > 0 {
+		msgs = append(msgs, fmt.Sprintf("%d more", e.Omitted))
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Add records an invalid field, beyond MaxFieldErrors it is only counted
+func (e *ValidationError) Add(field, message string) {
+	if len(e.Fields) >= MaxFieldErrors {
+		e.Omitted++
+		return
+	}
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns nil when no field was added, so it can be returned directly
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body
+type Problem struct {
+	Type          string       `json:"type"`
+	Title         string       `json:"title"`
+	Status        int          `json:"status"`
+	Detail        string       `json:"detail,omitempty"`
+	Instance      string       `json:"instance,omitempty"`
+	Code          Code         `json:"code"`
+	CorrelationID string       `json:"correlation_id,omitempty"`
+	Errors        []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem builds a problem for code, the detail must be safe to show to clients
+func NewProblem(status int, code Code, detail string) Problem {
+	return Problem{
+		Type:   "/problems/" + string(code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// FromError maps a domain error to a problem. Unknown errors become a generic 500
+// so internal details (SQL, hosts, stack) never reach the client.
+func FromError(err error) Problem {
+	var verr *ValidationError
+	switch {
+	case errors.As(err, &verr):
+		p := NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, "order failed validation")
+		if verr.Omitted > 0 {
+			p.Detail = fmt.Sprintf("order failed validation, %d more errors are not listed", verr.Omitted)
+		}
+		p.Errors = verr.Fields
+		return p
+	case errors.Is(err, ErrValidation):
+		return NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, "order failed validation")
+	case errors.Is(err, ErrNotFound):
+		return NewProblem(http.StatusNotFound, CodeNotFound, "order not found")
+	case errors.Is(err, ErrAlreadyExists):
+		return NewProblem(http.StatusConflict, CodeAlreadyExists, "order already exists")
+	case errors.Is(err, ErrTooLarge):
+		return NewProblem(http.StatusRequestEntityTooLarge, CodeTooLarge, "payload exceeds the size limit")
+	case errors.Is(err, ErrInvalidInput):
+		return NewProblem(http.StatusBadRequest, CodeInvalidInput, "invalid input data")
+	case errors.Is(err, ErrUnauthenticated):
+		return NewProblem(http.StatusUnauthorized, CodeUnauthenticated, "missing or invalid credentials")
+	case errors.Is(err, ErrForbidden):
+		return NewProblem(http.StatusForbidden, CodeForbidden, "not allowed for this role")
+	case errors.Is(err, ErrRateLimited):
+		return NewProblem(http.StatusTooManyRequests, CodeRateLimited, "too many requests, retry later")
+	default:
+		return NewProblem(http.StatusInternalServerError, CodeInternal, "internal error")
+	}
+}
+
+// Write sends p as application/problem+json
+func (p Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,130 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"strconv"
+	"time"
+)
+
+type Format string
+
+const (
+	// NDJSON writes one nested order per line
+	NDJSON Format = "ndjson"
+	// CSV writes one row per item, repeating the order and payment columns
+	CSV Format = "csv"
+	// Parquet writes one nested record per order
+	Parquet Format = "parquet"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case NDJSON, CSV, Parquet:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: unknown export format %q, want ndjson, csv or parquet", errdef.ErrInvalidInput, s)
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case Parquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Writer encodes orders one by one, Close flushes whatever the format buffers
+// (Parquet writes its footer) but does not close the underlying writer
+type Writer interface {
+	Write(o models.Order) error
+	Close() error
+}
+
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case NDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, fmt.Errorf("write csv header: %w", err)
+		}
+		return &csvWriter{w: cw}, nil
+	case Parquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown export format %q", errdef.ErrInvalidInput, f)
+	}
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(o models.Order) error {
+	return w.enc.Encode(o)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+var csvHeader = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address", "delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider", "payment_amount",
+	"payment_dt", "payment_bank", "payment_delivery_cost", "payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale",
+	"item_size", "item_total_price", "item_nm_id", "item_brand", "item_status",
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// Write writes a row per item, an order without items still gets one row with empty item columns
+func (w *csvWriter) Write(o models.Order) error {
+	d, p := o.Delivery, o.Payment
+	order := []string{
+		o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID,
+		o.DeliveryService, o.ShardKey, strconv.Itoa(o.SmID), o.DateCreated.UTC().Format(time.RFC3339), o.OofShard,
+		d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email,
+		p.Transaction, p.RequestID, p.Currency, p.Provider, strconv.Itoa(p.Amount),
+		strconv.FormatInt(p.PaymentDT, 10), p.Bank, strconv.Itoa(p.DeliveryCost), strconv.Itoa(p.GoodsTotal), strconv.Itoa(p.CustomFee),
+	}
+
+	if len(o.Items) == 0 {
+		return w.w.Write(append(order, make([]string, len(csvHeader)-len(order))...))
+	}
+	row := make([]string, len(csvHeader))
+	for _, it := range o.Items {
+		n := copy(row, order)
+		copy(row[n:], []string{
+			strconv.Itoa(it.ChrtID), it.TrackNumber, strconv.Itoa(it.Price), it.Rid, it.Name, strconv.Itoa(it.Sale),
+			it.Size, strconv.Itoa(it.TotalPrice), strconv.Itoa(it.NmID), it.Brand, strconv.Itoa(it.Status),
+		})
+		if err := w.w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"order_service/internal/models"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func testOrders() []models.Order {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.Order{
+		{
+			OrderUID: "o1", TrackNumber: "T1", CustomerID: "c1", DateCreated: created,
+			Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000"},
+			Payment:  models.Payment{Transaction: "o1", Currency: "USD", Amount: 300, GoodsTotal: 300},
+			Items: []models.Item{
+				{ChrtID: 1, TrackNumber: "T1", Name: "Mascaras", TotalPrice: 100},
+				{ChrtID: 2, TrackNumber: "T1", Name: "Shoes, red", TotalPrice: 200},
+			},
+		},
+		{OrderUID: "o2", TrackNumber: "T2", CustomerID: "c2", DateCreated: created},
+	}
+}
+
+func write(t *testing.T, f Format, orders []models.Order) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(f, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range orders {
+		if err := w.Write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readCSV(t *testing.T, b []byte) [][]string {
+	t.Helper()
+	rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 || !slices.Equal(rows[0], csvHeader) {
+		t.Fatalf("the first row is not the header: %q", rows)
+	}
+	return rows[1:]
+}
+
+func TestCSVRowPerItem(t *testing.T) {
+	rows := readCSV(t, write(t, CSV, testOrders()))
+	col := func(name string) int { return slices.Index(csvHeader, name) }
+
+	if len(rows) != 3 {
+		t.Fatalf("%d rows, want one per item and one for the order without items", len(rows))
+	}
+	for i, want := range []struct{ order, chrtID, name string }{
+		{"o1", "1", "Mascaras"},
+		{"o1", "2", "Shoes, red"},
+		{"o2", "", ""},
+	} {
+		row := rows[i]
+		if len(row) != len(csvHeader) {
+			t.Fatalf("row %d has %d columns, want %d", i, len(row), len(csvHeader))
+		}
+		if row[col("order_uid")] != want.order || row[col("item_chrt_id")] != want.chrtID || row[col("item_name")] != want.name {
+			t.Fatalf("row %d: %q", i, row)
+		}
+	}
+	//the order and payment columns repeat on every item row
+	if rows[1][col("payment_amount")] != "300" || rows[1][col("delivery_name")] != "Test Testov" {
+		t.Fatalf("order columns are not repeated: %q", rows[1])
+	}
+	if rows[0][col("date_created")] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("date_created %q", rows[0][col("date_created")])
+	}
+	//an order without items has empty item columns, not zeroes
+	for _, c := range csvHeader[col("item_chrt_id"):] {
+		if v := rows[2][col(c)]; v != "" {
+			t.Fatalf("%s of an order without items is %q", c, v)
+		}
+	}
+}
+
+func TestNDJSONRoundTrip(t *testing.T) {
+	dec := json.NewDecoder(bytes.NewReader(write(t, NDJSON, testOrders())))
+	var got []models.Order
+	for dec.More() {
+		var o models.Order
+		if err := dec.Decode(&o); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, o)
+	}
+	if !reflect.DeepEqual(got, testOrders()) {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	b := write(t, Parquet, testOrders())
+	got, err := parquet.Read[parquetOrder](bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("%d records, want 2", len(got))
+	}
+	for i, o := range testOrders() {
+		want := toParquet(o)
+		if !got[i].DateCreated.Equal(want.DateCreated) {
+			t.Fatalf("record %d: date_created %s, want %s", i, got[i].DateCreated, want.DateCreated)
+		}
+		got[i].DateCreated, want.DateCreated = time.Time{}, time.Time{}
+		//an empty list may read back as nil
+		if len(want.Items) == 0 {
+			got[i].Items, want.Items = nil, nil
+		}
+		if !reflect.DeepEqual(got[i], want) {
+			t.Fatalf("record %d:\n got %+v\nwant %+v", i, got[i], want)
+		}
+	}
+}
+
+func TestEmptyExports(t *testing.T) {
+	if b := write(t, NDJSON, nil); len(b) != 0 {
+		t.Fatalf("empty ndjson export: %q", b)
+	}
+	if rows := readCSV(t, write(t, CSV, nil)); len(rows) != 0 {
+		t.Fatalf("empty csv export has rows: %q", rows)
+	}
+	b := write(t, Parquet, nil)
+	got, err := parquet.Read[parquetOrder](bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("empty parquet export is not a valid file: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("empty parquet export has %d records", len(got))
+	}
+}
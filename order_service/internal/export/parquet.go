@@ -0,0 +1,135 @@
+package export
+
+import (
+	"io"
+	"order_service/internal/models"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroup bounds how many orders are buffered before a row group is written
+const parquetRowGroup = 10000
+
+type parquetOrder struct {
+	OrderUID          string          `parquet:"order_uid"`
+	TrackNumber       string          `parquet:"track_number"`
+	Entry             string          `parquet:"entry"`
+	Delivery          parquetDelivery `parquet:"delivery"`
+	Payment           parquetPayment  `parquet:"payment"`
+	Items             []parquetItem   `parquet:"items,list"`
+	Locale            string          `parquet:"locale"`
+	InternalSignature string          `parquet:"internal_signature"`
+	CustomerID        string          `parquet:"customer_id"`
+	DeliveryService   string          `parquet:"delivery_service"`
+	ShardKey          string          `parquet:"shardkey"`
+	SmID              int64           `parquet:"sm_id"`
+	DateCreated       time.Time       `parquet:"date_created,timestamp(millisecond)"`
+	OofShard          string          `parquet:"oof_shard"`
+}
+
+type parquetDelivery struct {
+	Name    string `parquet:"name"`
+	Phone   string `parquet:"phone"`
+	Zip     string `parquet:"zip"`
+	City    string `parquet:"city"`
+	Address string `parquet:"address"`
+	Region  string `parquet:"region"`
+	Email   string `parquet:"email"`
+}
+
+type parquetPayment struct {
+	Transaction  string `parquet:"transaction"`
+	RequestID    string `parquet:"request_id"`
+	Currency     string `parquet:"currency"`
+	Provider     string `parquet:"provider"`
+	Amount       int64  `parquet:"amount"`
+	PaymentDT    int64  `parquet:"payment_dt"`
+	Bank         string `parquet:"bank"`
+	DeliveryCost int64  `parquet:"delivery_cost"`
+	GoodsTotal   int64  `parquet:"goods_total"`
+	CustomFee    int64  `parquet:"custom_fee"`
+}
+
+type parquetItem struct {
+	ChrtID      int64  `parquet:"chrt_id"`
+	TrackNumber string `parquet:"track_number"`
+	Price       int64  `parquet:"price"`
+	Rid         string `parquet:"rid"`
+	Name        string `parquet:"name"`
+	Sale        int64  `parquet:"sale"`
+	Size        string `parquet:"size"`
+	TotalPrice  int64  `parquet:"total_price"`
+	NmID        int64  `parquet:"nm_id"`
+	Brand       string `parquet:"brand"`
+	Status      int64  `parquet:"status"`
+}
+
+type parquetWriter struct {
+	w   *parquet.GenericWriter[parquetOrder]
+	buf []parquetOrder
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w:   parquet.NewGenericWriter[parquetOrder](w, parquet.Compression(&parquet.Zstd), parquet.MaxRowsPerRowGroup(parquetRowGroup)),
+		buf: make([]parquetOrder, 0, 1),
+	}
+}
+
+func (w *parquetWriter) Write(o models.Order) error {
+	w.buf = append(w.buf[:0], toParquet(o))
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	return w.w.Close()
+}
+
+func toParquet(o models.Order) parquetOrder {
+	d, p := o.Delivery, o.Payment
+	po := parquetOrder{
+		OrderUID:          o.OrderUID,
+		TrackNumber:       o.TrackNumber,
+		Entry:             o.Entry,
+		Delivery:          parquetDelivery(d),
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerID:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		ShardKey:          o.ShardKey,
+		SmID:              int64(o.SmID),
+		DateCreated:       o.DateCreated,
+		OofShard:          o.OofShard,
+		Payment: parquetPayment{
+			Transaction:  p.Transaction,
+			RequestID:    p.RequestID,
+			Currency:     p.Currency,
+			Provider:     p.Provider,
+			Amount:       int64(p.Amount),
+			PaymentDT:    p.PaymentDT,
+			Bank:         p.Bank,
+			DeliveryCost: int64(p.DeliveryCost),
+			GoodsTotal:   int64(p.GoodsTotal),
+			CustomFee:    int64(p.CustomFee),
+		},
+		Items: make([]parquetItem, len(o.Items)),
+	}
+	for i, it := range o.Items {
+		po.Items[i] = parquetItem{
+			ChrtID:      int64(it.ChrtID),
+			TrackNumber: it.TrackNumber,
+			Price:       int64(it.Price),
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        int64(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int64(it.TotalPrice),
+			NmID:        int64(it.NmID),
+			Brand:       it.Brand,
+			Status:      int64(it.Status),
+		}
+	}
+	return po
+}
@@ -0,0 +1,182 @@
+package feed
+
+import (
+	"context"
+	"order_service/internal/models"
+	"sync"
+	"time"
+)
+
+// Summary is what the live feed shows for a saved order
+type Summary struct {
+	OrderUID        string    `json:"order_uid"`
+	TrackNumber     string    `json:"track_number"`
+	CustomerID      string    `json:"customer_id"`
+	DeliveryService string    `json:"delivery_service"`
+	Amount          int       `json:"amount"`
+	Currency        string    `json:"currency"`
+	Items           int       `json:"items"`
+	DateCreated     time.Time `json:"date_created"`
+}
+
+type Event struct {
+	ID      uint64
+	Summary Summary
+}
+
+// Filter selects events for a subscriber, zero values match everything
+type Filter struct {
+	CustomerID      string
+	DeliveryService string
+	MinAmount       int
+}
+
+func (f Filter) Match(s Summary) bool {
+	if f.CustomerID != "" && f.CustomerID != s.CustomerID {
+		return false
+	}
+	if f.DeliveryService != "" && f.DeliveryService != s.DeliveryService {
+		return false
+	}
+	return s.Amount >= f.MinAmount
+}
+
+func NewSummary(o models.Order) Summary {
+	return Summary{
+		OrderUID:        o.OrderUID,
+		TrackNumber:     o.TrackNumber,
+		CustomerID:      o.CustomerID,
+		DeliveryService: o.DeliveryService,
+		Amount:          o.Payment.Amount,
+		Currency:        o.Payment.Currency,
+		Items:           len(o.Items),
+		DateCreated:     o.DateCreated,
+	}
+}
+
+// Subscription receives events on C. C is closed when the subscriber is dropped for
+// being too slow (Dropped reports true) or the broker is closed.
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	filter Filter
+
+	dropped bool
+}
+
+// Broker fans saved orders out to live subscribers and keeps the last events in a
+// bounded ring buffer so reconnecting clients can resume from Last-Event-ID
+type Broker struct {
+	mu       sync.Mutex
+	nextID   uint64
+	ring     []Event
+	start    int
+	size     int
+	subs     map[*Subscription]struct{}
+	clientCh int
+	closed   bool
+}
+
+// NewBroker keeps bufferSize events for resume, every client may fall at most clientBuffer events behind
+func NewBroker(bufferSize, clientBuffer int) *Broker {
+	return &Broker{
+		nextID:   1,
+		ring:     make([]Event, max(bufferSize, 1)),
+		subs:     make(map[*Subscription]struct{}),
+		clientCh: max(clientBuffer, 1),
+	}
+}
+
+// OrderSaved publishes the order to the feed, it never blocks on slow subscribers
+func (b *Broker) OrderSaved(_ context.Context, order models.Order) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	ev := Event{ID: b.nextID, Summary: NewSummary(order)}
+	b.nextID++
+
+	b.ring[(b.start+b.size)%len(b.ring)] = ev
+	if b.size < len(b.ring) {
+		b.size++
+	} else {
+		b.start = (b.start + 1) % len(b.ring)
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.Match(ev.Summary) {
+			continue
+		}
+		select {
+		case sub.c <- ev:
+		default:
+			//backpressure: a client that can't keep up is disconnected instead of slowing everyone down
+			sub.dropped = true
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber. When lastID > 0 the buffered events after it that match
+// the filter are returned for replay; complete is false if some of them were already evicted.
+func (b *Broker) Subscribe(filter Filter, lastID uint64) (sub *Subscription, replay []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := make(chan Event, b.clientCh)
+	sub = &Subscription{C: c, c: c, filter: filter}
+	if b.closed {
+		close(c)
+		return sub, nil, true
+	}
+	b.subs[sub] = struct{}{}
+
+	complete = true
+	if lastID > 0 {
+		oldest := b.nextID
+		if b.size > 0 {
+			oldest = b.ring[b.start].ID
+		}
+		complete = lastID+1 >= oldest
+		for i := 0; i < b.size; i++ {
+			ev := b.ring[(b.start+i)%len(b.ring)]
+			if ev.ID > lastID && filter.Match(ev.Summary) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	return sub, replay, complete
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Dropped reports whether the subscription was closed because the client was too slow
+func (b *Broker) Dropped(sub *Subscription) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return sub.dropped
+}
+
+// Close disconnects every subscriber, it is meant to be called when the server shuts down
+// so long-lived streams don't hold the graceful shutdown until its deadline
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
@@ -0,0 +1,41 @@
+package generator
+
+type city struct {
+	name   string
+	region string
+}
+
+type product struct {
+	name  string
+	brand string
+	price int
+}
+
+var (
+	cities = []city{
+		{"Moscow", "Moscow"},
+		{"Saint Petersburg", "Leningrad Oblast"},
+		{"Kazan", "Tatarstan"},
+		{"Novosibirsk", "Novosibirsk Oblast"},
+		{"Yekaterinburg", "Sverdlovsk Oblast"},
+		{"Kiryat Mozkin", "Kraiot"},
+		{"Almaty", "Almaty Region"},
+		{"Tashkent", "Tashkent Region"},
+	}
+	streets          = []string{"Ploshad Mira", "Lenina", "Tverskaya", "Nevsky Prospekt", "Sadovaya", "Gagarina", "Pushkina"}
+	firstNames       = []string{"Ivan", "Anna", "Dmitry", "Olga", "Sergey", "Maria", "Alexey", "Elena", "Test"}
+	lastNames        = []string{"Ivanov", "Petrova", "Smirnov", "Kuznetsova", "Popov", "Sokolova", "Testov"}
+	mailDomains      = []string{"gmail.com", "mail.ru", "yandex.ru", "example.com"}
+	deliveryServices = []string{"meest", "cdek", "boxberry", "pochta", "wb"}
+	banks            = []string{"alpha", "sber", "tinkoff", "vtb"}
+	products         = []product{
+		{"Mascaras", "Vivienne Sabo", 453},
+		{"Sneakers", "Nike", 7990},
+		{"T-shirt", "Uniqlo", 990},
+		{"Backpack", "Xiaomi", 2490},
+		{"Headphones", "JBL", 3590},
+		{"Notebook", "Moleskine", 1290},
+		{"Phone case", "Spigen", 790},
+		{"Coffee beans", "Lavazza", 1190},
+	}
+)
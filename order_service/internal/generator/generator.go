@@ -0,0 +1,190 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"order_service/internal/models"
+	"strings"
+	"time"
+)
+
+// Corruption is the way a deliberately broken record is broken, "" for a valid one
+type Corruption string
+
+const (
+	// Malformed cuts the JSON in the middle, it fails decoding
+	Malformed Corruption = "malformed_json"
+	// WrongType turns a number into a string, it fails decoding
+	WrongType Corruption = "wrong_type"
+	// MissingField drops a required field, it fails validation
+	MissingField Corruption = "missing_field"
+	// NegativeAmount makes the payment negative, it fails validation
+	NegativeAmount Corruption = "negative_amount"
+	// NoItems drops every item, it fails validation
+	NoItems Corruption = "no_items"
+)
+
+var corruptions = []Corruption{Malformed, WrongType, MissingField, NegativeAmount, NoItems}
+
+type Options struct {
+	// Seed makes the output reproducible, the same seed and Start give the same records
+	Seed uint64
+	// MinItems and MaxItems bound the items per order
+	MinItems int
+	MaxItems int
+	// CorruptRate is the share of records in [0, 1] that are broken on purpose
+	CorruptRate float64
+	// Start is the creation time of the first order, the next ones are a few seconds apart
+	Start time.Time
+}
+
+// Generator makes realistic orders: the items share the order's track number, totals add up
+// and the payment belongs to the order. It is not safe for concurrent use.
+type Generator struct {
+	opts Options
+	rnd  *rand.Rand
+	now  time.Time
+}
+
+func New(opts Options) (*Generator, error) {
+	if opts.MinItems < 1 || opts.MaxItems < opts.MinItems {
+		return nil, fmt.Errorf("generator: items per order must satisfy 1 <= min <= max, got %d..%d", opts.MinItems, opts.MaxItems)
+	}
+	if opts.CorruptRate < 0 || opts.CorruptRate > 1 {
+		return nil, fmt.Errorf("generator: corrupt rate must be in [0, 1], got %v", opts.CorruptRate)
+	}
+	return &Generator{
+		opts: opts,
+		rnd:  rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)),
+		now:  opts.Start.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// Order returns the next valid order
+func (g *Generator) Order() models.Order {
+	g.now = g.now.Add(time.Duration(1+g.rnd.IntN(5)) * time.Second)
+
+	uid := g.hex(19) + "test"
+	track := "WBIL" + g.upper(10)
+	city := pick(g.rnd, cities)
+	first, last := pick(g.rnd, firstNames), pick(g.rnd, lastNames)
+
+	o := models.Order{
+		OrderUID:    uid,
+		TrackNumber: track,
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    first + " " + last,
+			Phone:   fmt.Sprintf("+79%09d", g.rnd.IntN(1e9)),
+			Zip:     fmt.Sprintf("%06d", 100000+g.rnd.IntN(900000)),
+			City:    city.name,
+			Address: fmt.Sprintf("%s %d", pick(g.rnd, streets), 1+g.rnd.IntN(150)),
+			Region:  city.region,
+			Email:   fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), g.rnd.IntN(1000), pick(g.rnd, mailDomains)),
+		},
+		Locale:          pick(g.rnd, []string{"en", "ru"}),
+		CustomerID:      fmt.Sprintf("customer-%d", 1+g.rnd.IntN(5000)),
+		DeliveryService: pick(g.rnd, deliveryServices),
+		ShardKey:        fmt.Sprint(g.rnd.IntN(10)),
+		SmID:            g.rnd.IntN(100),
+		DateCreated:     g.now,
+		OofShard:        fmt.Sprint(1 + g.rnd.IntN(2)),
+	}
+
+	n := g.opts.MinItems + g.rnd.IntN(g.opts.MaxItems-g.opts.MinItems+1)
+	goods := 0
+	for range n {
+		product := pick(g.rnd, products)
+		price := product.price + g.rnd.IntN(product.price/2+1)
+		sale := pick(g.rnd, []int{0, 0, 10, 15, 30, 50})
+		total := price * (100 - sale) / 100
+		goods += total
+		o.Items = append(o.Items, models.Item{
+			ChrtID:      1000000 + g.rnd.IntN(9000000),
+			TrackNumber: track,
+			Price:       price,
+			Rid:         g.hex(19) + "test",
+			Name:        product.name,
+			Sale:        sale,
+			Size:        pick(g.rnd, []string{"0", "S", "M", "L", "XL"}),
+			TotalPrice:  total,
+			NmID:        1000000 + g.rnd.IntN(9000000),
+			Brand:       product.brand,
+			Status:      202,
+		})
+	}
+
+	deliveryCost := pick(g.rnd, []int{0, 150, 300, 500, 1500})
+	o.Payment = models.Payment{
+		Transaction:  uid,
+		Currency:     pick(g.rnd, []string{"USD", "RUB"}),
+		Provider:     pick(g.rnd, []string{"wbpay", "sbp", "card"}),
+		Amount:       goods + deliveryCost,
+		PaymentDT:    g.now.Unix(),
+		Bank:         pick(g.rnd, banks),
+		DeliveryCost: deliveryCost,
+		GoodsTotal:   goods,
+	}
+	return o
+}
+
+// Record returns the next order as JSON, broken with probability CorruptRate.
+// The order is returned even for broken records so callers can key them.
+func (g *Generator) Record() ([]byte, models.Order, Corruption, error) {
+	o := g.Order()
+	var c Corruption
+	if g.opts.CorruptRate > 0 && g.rnd.Float64() < g.opts.CorruptRate {
+		c = pick(g.rnd, corruptions)
+	}
+
+	switch c {
+	case NegativeAmount:
+		o.Payment.Amount = -1 - g.rnd.IntN(1000)
+	case NoItems:
+		o.Items = nil
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, o, c, err
+	}
+
+	switch c {
+	case Malformed:
+		b = b[:len(b)/2]
+	case WrongType:
+		b = []byte(strings.Replace(string(b), `"sm_id":`, `"sm_id":"x`, 1))
+		b = []byte(strings.Replace(string(b), `,"date_created"`, `","date_created"`, 1))
+	case MissingField:
+		var m map[string]any
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, o, c, err
+		}
+		delete(m, pick(g.rnd, []string{"track_number", "customer_id", "delivery_service", "locale"}))
+		if b, err = json.Marshal(m); err != nil {
+			return nil, o, c, err
+		}
+	}
+	return b, o, c, nil
+}
+
+func (g *Generator) hex(n int) string {
+	const digits = "0123456789abcdef"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = digits[g.rnd.IntN(len(digits))]
+	}
+	return string(b)
+}
+
+func (g *Generator) upper(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('A' + g.rnd.IntN(26))
+	}
+	return string(b)
+}
+
+func pick[T any](rnd *rand.Rand, from []T) T {
+	return from[rnd.IntN(len(from))]
+}
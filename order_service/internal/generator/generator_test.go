@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSameSeedAndStartGiveSameRecords(t *testing.T) {
+	opts := Options{Seed: 42, MinItems: 1, MaxItems: 5, CorruptRate: 0.3, Start: start}
+	a, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 100 {
+		ra, _, ca, err := a.Record()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rb, _, cb, err := b.Record()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ra, rb) || ca != cb {
+			t.Fatalf("record %d differs:\n%s\n%s", i, ra, rb)
+		}
+	}
+}
+
+func TestOrdersAreConsistent(t *testing.T) {
+	gen, err := New(Options{Seed: 7, MinItems: 2, MaxItems: 4, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := start
+	for range 500 {
+		o := gen.Order()
+		if err := o.Validate(); err != nil {
+			t.Fatalf("order %s is invalid: %v", o.OrderUID, err)
+		}
+		if n := len(o.Items); n < 2 || n > 4 {
+			t.Fatalf("order %s has %d items, want 2 to 4", o.OrderUID, n)
+		}
+		goods := 0
+		for _, it := range o.Items {
+			goods += it.TotalPrice
+			if it.TrackNumber != o.TrackNumber {
+				t.Fatalf("item track number %s, order %s", it.TrackNumber, o.TrackNumber)
+			}
+			if it.TotalPrice != it.Price*(100-it.Sale)/100 {
+				t.Fatalf("item total %d of price %d with sale %d", it.TotalPrice, it.Price, it.Sale)
+			}
+		}
+		p := o.Payment
+		if p.GoodsTotal != goods || p.Amount != p.GoodsTotal+p.DeliveryCost {
+			t.Fatalf("order %s: goods_total %d, items sum to %d, amount %d, delivery %d", o.OrderUID, p.GoodsTotal, goods, p.Amount, p.DeliveryCost)
+		}
+		if p.Transaction != o.OrderUID || p.PaymentDT != o.DateCreated.Unix() {
+			t.Fatalf("payment %+v does not belong to order %s", p, o.OrderUID)
+		}
+		if !o.DateCreated.After(prev) {
+			t.Fatalf("date_created %s is not after %s", o.DateCreated, prev)
+		}
+		prev = o.DateCreated
+	}
+}
+
+// TestCorruptionsFailAsDocumented checks the stage each kind of broken record fails at
+func TestCorruptionsFailAsDocumented(t *testing.T) {
+	failsDecoding := map[Corruption]bool{Malformed: true, WrongType: true}
+	gen, err := New(Options{Seed: 3, MinItems: 1, MaxItems: 3, CorruptRate: 1, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[Corruption]bool{}
+	for range 200 {
+		b, _, c, err := gen.Record()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c == "" {
+			t.Fatal("CorruptRate 1 produced a valid record")
+		}
+		seen[c] = true
+
+		var o models.Order
+		decodeErr := json.Unmarshal(b, &o)
+		if failsDecoding[c] {
+			if decodeErr == nil {
+				t.Fatalf("%s record decoded: %s", c, b)
+			}
+			continue
+		}
+		if decodeErr != nil {
+			t.Fatalf("%s record should decode: %v", c, decodeErr)
+		}
+		var verr *errdef.ValidationError
+		if err := o.Validate(); !errors.As(err, &verr) {
+			t.Fatalf("%s record passed validation: %s", c, b)
+		}
+	}
+	for _, c := range corruptions {
+		if !seen[c] {
+			t.Errorf("%s was never produced", c)
+		}
+	}
+}
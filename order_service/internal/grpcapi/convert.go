@@ -0,0 +1,122 @@
+package grpcapi
+
+import (
+	"order_service/internal/grpcapi/orderpb"
+	"order_service/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProto(o models.Order) *orderpb.Order {
+	items := make([]*orderpb.Item, 0, len(o.Items))
+	for _, it := range o.Items {
+		items = append(items, &orderpb.Item{
+			ChrtId:      int64(it.ChrtID),
+			TrackNumber: it.TrackNumber,
+			Price:       int64(it.Price),
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        int64(it.Sale),
+			Size:        it.Size,
+			TotalPrice:  int64(it.TotalPrice),
+			NmId:        int64(it.NmID),
+			Brand:       it.Brand,
+			Status:      int64(it.Status),
+		})
+	}
+	return &orderpb.Order{
+		OrderUid:    o.OrderUID,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &orderpb.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &orderpb.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDT,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.ShardKey,
+		SmId:              int64(o.SmID),
+		DateCreated:       timestamppb.New(o.DateCreated),
+		OofShard:          o.OofShard,
+	}
+}
+
+// fromProto converts a request order, nil sub-messages become zero values and are left to validation
+func fromProto(o *orderpb.Order) models.Order {
+	d := o.GetDelivery()
+	p := o.GetPayment()
+	items := make([]models.Item, 0, len(o.GetItems()))
+	for _, it := range o.GetItems() {
+		items = append(items, models.Item{
+			ChrtID:      int(it.GetChrtId()),
+			TrackNumber: it.GetTrackNumber(),
+			Price:       int(it.GetPrice()),
+			Rid:         it.GetRid(),
+			Name:        it.GetName(),
+			Sale:        int(it.GetSale()),
+			Size:        it.GetSize(),
+			TotalPrice:  int(it.GetTotalPrice()),
+			NmID:        int(it.GetNmId()),
+			Brand:       it.GetBrand(),
+			Status:      int(it.GetStatus()),
+		})
+	}
+	order := models.Order{
+		OrderUID:    o.GetOrderUid(),
+		TrackNumber: o.GetTrackNumber(),
+		Entry:       o.GetEntry(),
+		Delivery: models.Delivery{
+			Name:    d.GetName(),
+			Phone:   d.GetPhone(),
+			Zip:     d.GetZip(),
+			City:    d.GetCity(),
+			Address: d.GetAddress(),
+			Region:  d.GetRegion(),
+			Email:   d.GetEmail(),
+		},
+		Payment: models.Payment{
+			Transaction:  p.GetTransaction(),
+			RequestID:    p.GetRequestId(),
+			Currency:     p.GetCurrency(),
+			Provider:     p.GetProvider(),
+			Amount:       int(p.GetAmount()),
+			PaymentDT:    p.GetPaymentDt(),
+			Bank:         p.GetBank(),
+			DeliveryCost: int(p.GetDeliveryCost()),
+			GoodsTotal:   int(p.GetGoodsTotal()),
+			CustomFee:    int(p.GetCustomFee()),
+		},
+		Items:             items,
+		Locale:            o.GetLocale(),
+		InternalSignature: o.GetInternalSignature(),
+		CustomerID:        o.GetCustomerId(),
+		DeliveryService:   o.GetDeliveryService(),
+		ShardKey:          o.GetShardkey(),
+		SmID:              int(o.GetSmId()),
+		OofShard:          o.GetOofShard(),
+	}
+	if o.GetDateCreated() != nil {
+		order.DateCreated = o.GetDateCreated().AsTime()
+	}
+	return order
+}
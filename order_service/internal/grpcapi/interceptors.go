@@ -0,0 +1,78 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"order_service/internal/auth"
+	"order_service/internal/grpcapi/orderpb"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// correlationMetadata is the grpc metadata key for the correlation id (keys are lower case)
+const correlationMetadata = "x-request-id"
+
+// methodRoles is the least role each rpc needs
+var methodRoles = map[string]models.Role{
+	orderpb.OrderService_GetOrder_FullMethodName:       models.RoleReader,
+	orderpb.OrderService_BatchGetOrders_FullMethodName: models.RoleReader,
+	orderpb.OrderService_ListOrders_FullMethodName:     models.RoleReader,
+	orderpb.OrderService_SaveOrder_FullMethodName:      models.RoleWriter,
+}
+
+// NewGRPCServer builds a grpc server with tracing, correlation ids, request logging,
+// authentication and panic recovery, and registers srv on it. A nil authn disables authentication.
+func NewGRPCServer(srv *Server, authn *auth.Authenticator) *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(srv.unaryLogging, auth.UnaryInterceptor(authn, methodRoles, srv.logger), srv.unaryRecover),
+	)
+	orderpb.RegisterOrderServiceServer(gs, srv)
+	return gs
+}
+
+func (s *Server) unaryLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(correlationMetadata); len(v) > 0 && len(v[0]) <= 128 {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = logging.NewCorrelationID()
+	}
+	ctx = logging.WithCorrelationID(ctx, id)
+	grpc.SetHeader(ctx, metadata.Pairs(correlationMetadata, id))
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	attrs := []any{"method", info.FullMethod, "code", code.String(), "duration", time.Since(start)}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	s.logger.Log(ctx, level, "grpc request", attrs...)
+	return resp, err
+}
+
+func (s *Server) unaryRecover(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.ErrorContext(ctx, "grpc handler panicked", "method", info.FullMethod, "panic", r)
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
@@ -0,0 +1,156 @@
+package grpcapi
+
+//go:generate buf generate
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"order_service/internal/errdef"
+	"order_service/internal/grpcapi/orderpb"
+	"order_service/internal/models"
+	"order_service/internal/pii"
+	"order_service/internal/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements orderpb.OrderServiceServer on top of service.OrderService
+type Server struct {
+	orderpb.UnimplementedOrderServiceServer
+
+	service *service.OrderService
+	pii     *pii.Masker
+	logger  *slog.Logger
+}
+
+func NewServer(s *service.OrderService, masker *pii.Masker, logger *slog.Logger) *Server {
+	return &Server{service: s, pii: masker, logger: logger.With("component", "grpc")}
+}
+
+func (s *Server) GetOrder(ctx context.Context, req *orderpb.GetOrderRequest) (*orderpb.Order, error) {
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+	order, err := s.service.GetOrder(ctx, req.GetOrderUid())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(s.pii.Order(ctx, order)), nil
+}
+
+func (s *Server) BatchGetOrders(ctx context.Context, req *orderpb.BatchGetOrdersRequest) (*orderpb.BatchGetOrdersResponse, error) {
+	if len(req.GetOrderUids()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_uids must not be empty")
+	}
+	orders, missing, err := s.service.GetOrders(ctx, req.GetOrderUids())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &orderpb.BatchGetOrdersResponse{Orders: make([]*orderpb.Order, 0, len(orders)), MissingUids: missing}
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, toProto(s.pii.Order(ctx, o)))
+	}
+	return resp, nil
+}
+
+func (s *Server) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	if req.GetPageSize() < 0 || req.GetPageSize() > service.MaxListLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 0 and %d", service.MaxListLimit)
+	}
+	after, err := decodePageToken(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+
+	filter := models.OrderFilter{
+		CustomerID:      req.GetCustomerId(),
+		DeliveryService: req.GetDeliveryService(),
+		MinAmount:       int(req.GetMinAmount()),
+		AfterUID:        after,
+		Limit:           int(req.GetPageSize()),
+	}
+	if req.GetCreatedFrom() != nil {
+		filter.CreatedFrom = req.GetCreatedFrom().AsTime()
+	}
+	if req.GetCreatedTo() != nil {
+		filter.CreatedTo = req.GetCreatedTo().AsTime()
+	}
+	if filter.Limit == 0 {
+		filter.Limit = service.MaxListLimit
+	}
+
+	orders, err := s.service.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &orderpb.ListOrdersResponse{Orders: make([]*orderpb.Order, 0, len(orders))}
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, toProto(s.pii.Order(ctx, o)))
+	}
+	//a full page means there may be more
+	if len(orders) == filter.Limit {
+		resp.NextPageToken = encodePageToken(orders[len(orders)-1].OrderUID)
+	}
+	return resp, nil
+}
+
+func (s *Server) SaveOrder(ctx context.Context, req *orderpb.SaveOrderRequest) (*orderpb.SaveOrderResponse, error) {
+	if req.GetOrder() == nil {
+		return nil, status.Error(codes.InvalidArgument, "order is required")
+	}
+	if err := s.service.SaveOrder(ctx, fromProto(req.GetOrder())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &orderpb.SaveOrderResponse{}, nil
+}
+
+// toStatus maps domain errors to grpc codes, anything unknown becomes a generic Internal
+// so storage details never reach the caller
+func toStatus(err error) error {
+	var verr *errdef.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		st := status.New(codes.InvalidArgument, "order failed validation")
+		br := &errdetails.BadRequest{}
+		for _, f := range verr.Fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		if withDetails, derr := st.WithDetails(br); derr == nil {
+			st = withDetails
+		}
+		return st.Err()
+	case errors.Is(err, errdef.ErrValidation):
+		return status.Error(codes.InvalidArgument, "invalid order")
+	case errors.Is(err, errdef.ErrInvalidInput):
+		//invalid input errors describe the caller's own request
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, errdef.ErrNotFound):
+		return status.Error(codes.NotFound, "order not found")
+	case errors.Is(err, errdef.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, "order already exists")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func encodePageToken(afterUID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(afterUID))
+}
+
+func decodePageToken(token string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("decode page token: %w", err)
+	}
+	return string(b), nil
+}
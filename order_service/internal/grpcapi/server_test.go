@@ -0,0 +1,331 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"order_service/internal/auth"
+	"order_service/internal/errdef"
+	"order_service/internal/generator"
+	"order_service/internal/grpcapi/orderpb"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"order_service/internal/pii"
+	"order_service/internal/ports/adapters/cache"
+	"order_service/internal/ports/adapters/storage"
+	"order_service/internal/ports/storagetest"
+	"order_service/internal/service"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mapKeyStore map[string]models.APIKey
+
+func (s mapKeyStore) GetByHash(_ context.Context, hash string) (models.APIKey, error) {
+	if k, ok := s[hash]; ok {
+		return k, nil
+	}
+	return models.APIKey{}, errdef.ErrNotFound
+}
+func (s mapKeyStore) Create(context.Context, models.APIKey) error   { return nil }
+func (s mapKeyStore) List(context.Context) ([]models.APIKey, error) { return nil, nil }
+func (s mapKeyStore) Revoke(context.Context, string) error          { return nil }
+
+// testServer is an OrderService client over bufconn with a key for every role
+type testServer struct {
+	client  orderpb.OrderServiceClient
+	storage *storage.OrderStorageMemory
+	gen     *generator.Generator
+	keys    map[models.Role]string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ts := &testServer{storage: storage.NewOrderStorageMemory(), keys: map[models.Role]string{}}
+	gen, err := generator.New(generator.Options{Seed: 1, MinItems: 1, MaxItems: 3, Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.gen = gen
+
+	store := mapKeyStore{}
+	for _, role := range []models.Role{models.RoleReader, models.RoleWriter, models.RoleAdmin} {
+		plain, key, err := auth.NewKey(string(role), role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store[key.Hash] = key
+		ts.keys[role] = plain
+	}
+
+	svc := service.NewOrderService(ts.storage, cache.NewOrderCacheMemory(time.Hour), logging.Nop()).WithValidation(true).WithMaxBatch(3)
+	masker := pii.NewMasker(pii.DefaultPolicy(), models.RoleAdmin)
+	gs := NewGRPCServer(NewServer(svc, masker, logging.Nop()), auth.NewAuthenticator(store, "", time.Minute))
+	lis := bufconn.Listen(1 << 20)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ts.client = orderpb.NewOrderServiceClient(conn)
+	return ts
+}
+
+// as returns a context calling with the key of role
+func (ts *testServer) as(role models.Role) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", ts.keys[role])
+}
+
+// saveOrders stores n generated orders directly in the storage
+func (ts *testServer) saveOrders(t *testing.T, n int) []models.Order {
+	t.Helper()
+	orders := make([]models.Order, 0, n)
+	for range n {
+		o := ts.gen.Order()
+		if err := ts.storage.SaveOrder(context.Background(), o); err != nil {
+			t.Fatal(err)
+		}
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+func TestMethodRoles(t *testing.T) {
+	ts := newTestServer(t)
+	o := ts.saveOrders(t, 1)[0]
+
+	calls := map[string]func(ctx context.Context) error{
+		"GetOrder": func(ctx context.Context) error {
+			_, err := ts.client.GetOrder(ctx, &orderpb.GetOrderRequest{OrderUid: o.OrderUID})
+			return err
+		},
+		"BatchGetOrders": func(ctx context.Context) error {
+			_, err := ts.client.BatchGetOrders(ctx, &orderpb.BatchGetOrdersRequest{OrderUids: []string{o.OrderUID}})
+			return err
+		},
+		"ListOrders": func(ctx context.Context) error {
+			_, err := ts.client.ListOrders(ctx, &orderpb.ListOrdersRequest{})
+			return err
+		},
+		//a duplicate gets past the role check without changing the storage
+		"SaveOrder": func(ctx context.Context) error {
+			_, err := ts.client.SaveOrder(ctx, &orderpb.SaveOrderRequest{Order: toProto(o)})
+			return err
+		},
+	}
+	tests := []struct {
+		method string
+		role   models.Role
+		code   codes.Code
+	}{
+		{"GetOrder", "", codes.Unauthenticated},
+		{"GetOrder", models.RoleReader, codes.OK},
+		{"BatchGetOrders", "", codes.Unauthenticated},
+		{"BatchGetOrders", models.RoleReader, codes.OK},
+		{"ListOrders", "", codes.Unauthenticated},
+		{"ListOrders", models.RoleReader, codes.OK},
+		{"SaveOrder", "", codes.Unauthenticated},
+		{"SaveOrder", models.RoleReader, codes.PermissionDenied},
+		{"SaveOrder", models.RoleWriter, codes.AlreadyExists},
+		{"SaveOrder", models.RoleAdmin, codes.AlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s", tt.method, tt.role), func(t *testing.T) {
+			ctx := context.Background()
+			if tt.role != "" {
+				ctx = ts.as(tt.role)
+			}
+			if code := status.Code(calls[tt.method](ctx)); code != tt.code {
+				t.Fatalf("code %s, want %s", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	ts := newTestServer(t)
+	saved := ts.saveOrders(t, 1)[0]
+	ctx := ts.as(models.RoleAdmin)
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"get without uid", func() error {
+			_, err := ts.client.GetOrder(ctx, &orderpb.GetOrderRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"get missing", func() error {
+			_, err := ts.client.GetOrder(ctx, &orderpb.GetOrderRequest{OrderUid: "missing"})
+			return err
+		}, codes.NotFound},
+		{"empty batch", func() error {
+			_, err := ts.client.BatchGetOrders(ctx, &orderpb.BatchGetOrdersRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"batch over the limit", func() error {
+			_, err := ts.client.BatchGetOrders(ctx, &orderpb.BatchGetOrdersRequest{OrderUids: []string{"a", "b", "c", "d"}})
+			return err
+		}, codes.InvalidArgument},
+		{"negative page size", func() error {
+			_, err := ts.client.ListOrders(ctx, &orderpb.ListOrdersRequest{PageSize: -1})
+			return err
+		}, codes.InvalidArgument},
+		{"page size over the limit", func() error {
+			_, err := ts.client.ListOrders(ctx, &orderpb.ListOrdersRequest{PageSize: service.MaxListLimit + 1})
+			return err
+		}, codes.InvalidArgument},
+		{"malformed page token", func() error {
+			_, err := ts.client.ListOrders(ctx, &orderpb.ListOrdersRequest{PageToken: "not base64!"})
+			return err
+		}, codes.InvalidArgument},
+		{"save without order", func() error {
+			_, err := ts.client.SaveOrder(ctx, &orderpb.SaveOrderRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"save duplicate", func() error {
+			_, err := ts.client.SaveOrder(ctx, &orderpb.SaveOrderRequest{Order: toProto(saved)})
+			return err
+		}, codes.AlreadyExists},
+		{"save new", func() error {
+			_, err := ts.client.SaveOrder(ctx, &orderpb.SaveOrderRequest{Order: toProto(ts.gen.Order())})
+			return err
+		}, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.code {
+				t.Fatalf("code %s, want %s", code, tt.code)
+			}
+		})
+	}
+}
+
+// TestSaveOrderValidationDetails checks that failed fields come back as a BadRequest detail
+func TestSaveOrderValidationDetails(t *testing.T) {
+	ts := newTestServer(t)
+	o := ts.gen.Order()
+	o.Payment.Amount = -1
+
+	_, err := ts.client.SaveOrder(ts.as(models.RoleWriter), &orderpb.SaveOrderRequest{Order: toProto(o)})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code %s, want InvalidArgument: %v", st.Code(), err)
+	}
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok && len(br.GetFieldViolations()) > 0 {
+			return
+		}
+	}
+	t.Fatalf("no field violations in %v", st.Details())
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{&errdef.ValidationError{Fields: []errdef.FieldError{{Field: "order_uid", Message: "required"}}}, codes.InvalidArgument},
+		{errdef.ErrValidation, codes.InvalidArgument},
+		{fmt.Errorf("%w: too many ids", errdef.ErrInvalidInput), codes.InvalidArgument},
+		{fmt.Errorf("order x: %w", errdef.ErrNotFound), codes.NotFound},
+		{errdef.ErrAlreadyExists, codes.AlreadyExists},
+		{fmt.Errorf("query: %w", context.Canceled), codes.Canceled},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("dial tcp 10.0.0.1:5432: connection refused"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			if st.Code() != tt.code {
+				t.Fatalf("code %s, want %s", st.Code(), tt.code)
+			}
+			if tt.code == codes.Internal && st.Message() != "internal error" {
+				t.Fatalf("internal error leaked %q", st.Message())
+			}
+		})
+	}
+}
+
+func TestListOrdersPaging(t *testing.T) {
+	ts := newTestServer(t)
+	var want []string
+	for _, o := range ts.saveOrders(t, 7) {
+		want = append(want, o.OrderUID)
+	}
+	slices.Sort(want)
+
+	var got []string
+	var pages int
+	req := &orderpb.ListOrdersRequest{PageSize: 3}
+	for {
+		resp, err := ts.client.ListOrders(ts.as(models.RoleReader), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		if len(resp.GetOrders()) > 3 {
+			t.Fatalf("page %d has %d orders", pages, len(resp.GetOrders()))
+		}
+		for _, o := range resp.GetOrders() {
+			got = append(got, o.GetOrderUid())
+		}
+		if resp.GetNextPageToken() == "" {
+			break
+		}
+		req.PageToken = resp.GetNextPageToken()
+	}
+	if pages != 3 || !slices.Equal(got, want) {
+		t.Fatalf("%d pages with %v, want 3 pages with %v", pages, got, want)
+	}
+}
+
+// TestOrderConversion checks that orders survive the grpc api both ways and are masked for readers
+func TestOrderConversion(t *testing.T) {
+	ts := newTestServer(t)
+	o := ts.gen.Order()
+	if _, err := ts.client.SaveOrder(ts.as(models.RoleWriter), &orderpb.SaveOrderRequest{Order: toProto(o)}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := ts.storage.GetOrderByID(context.Background(), o.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storagetest.AssertOrder(t, stored, o)
+
+	got, err := ts.client.GetOrder(ts.as(models.RoleAdmin), &orderpb.GetOrderRequest{OrderUid: o.OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storagetest.AssertOrder(t, fromProto(got), o)
+
+	masked, err := ts.client.GetOrder(ts.as(models.RoleReader), &orderpb.GetOrderRequest{OrderUid: o.OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masked.GetDelivery().GetPhone() == o.Delivery.Phone || masked.GetOrderUid() != o.OrderUID {
+		t.Fatalf("reader got delivery %v", masked.GetDelivery())
+	}
+
+	//nil sub-messages are zero values, not a panic
+	if empty := fromProto(&orderpb.Order{OrderUid: "x"}); empty.OrderUID != "x" || len(empty.Items) != 0 || !empty.DateCreated.IsZero() {
+		t.Fatalf("empty order %+v", empty)
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/auth"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"order_service/internal/pii"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mapKeyStore map[string]models.APIKey
+
+func (s mapKeyStore) GetByHash(_ context.Context, hash string) (models.APIKey, error) {
+	if k, ok := s[hash]; ok {
+		return k, nil
+	}
+	return models.APIKey{}, errdef.ErrNotFound
+}
+func (s mapKeyStore) Create(context.Context, models.APIKey) error   { return nil }
+func (s mapKeyStore) List(context.Context) ([]models.APIKey, error) { return nil, nil }
+func (s mapKeyStore) Revoke(context.Context, string) error          { return nil }
+
+// TestGetOrderCachingVariesByCaller checks that callers with different masking don't share a cached copy
+func TestGetOrderCachingVariesByCaller(t *testing.T) {
+	keys := mapKeyStore{}
+	plain := map[models.Role]string{}
+	for _, role := range []models.Role{models.RoleReader, models.RoleAdmin} {
+		p, key, err := auth.NewKey(string(role), role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[key.Hash] = key
+		plain[role] = p
+	}
+
+	h := newTestHandler(t)
+	o := h.saveOrders(t, 1)[0]
+	masker := pii.NewMasker(pii.DefaultPolicy(), models.RoleAdmin)
+	srv := httptest.NewServer(h.WithAuth(auth.NewAuthenticator(keys, "", time.Minute)).WithPII(masker).SetRoutes())
+	defer srv.Close()
+
+	get := func(role models.Role, etag string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/order/"+o.OrderUID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(auth.APIKeyHeader, plain[role])
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	admin := get(models.RoleAdmin, "")
+	if admin.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", admin.StatusCode)
+	}
+	vary := strings.Join(admin.Header.Values("Vary"), ", ")
+	if !strings.Contains(vary, "Authorization") || !strings.Contains(vary, auth.APIKeyHeader) {
+		t.Fatalf("Vary %q does not name the credentials", vary)
+	}
+	if lm, want := admin.Header.Get("Last-Modified"), o.DateCreated.UTC().Format(http.TimeFormat); lm != want {
+		t.Fatalf("Last-Modified %q, want the creation date %q", lm, want)
+	}
+
+	if resp := get(models.RoleAdmin, admin.Header.Get("ETag")); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("same caller revalidating: status %d, want 304", resp.StatusCode)
+	}
+	//the reader's copy is masked, the admin's tag must not match it
+	if resp := get(models.RoleReader, admin.Header.Get("ETag")); resp.StatusCode != http.StatusOK {
+		t.Fatalf("reader revalidating the admin's copy: status %d, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/decoding"
+	"order_service/internal/errdef"
+	"order_service/internal/handler/openapi"
+	"order_service/internal/orderschema"
+	"strings"
+	"testing"
+)
+
+// TestSaveOrderBodyPolicy checks the limit and the strict policy behind the openapi validator
+func TestSaveOrderBodyPolicy(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := decoding.New(4096, decoding.Reject, "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHandler(t)
+	srv := httptest.NewServer(h.WithOpenAPI(spec).WithDecoder(decoder).SetRoutes())
+	defer srv.Close()
+
+	order := func() string {
+		b, err := json.Marshal(h.gen.Order())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	withField := func(o string) string {
+		return strings.TrimSuffix(o, "}") + `,"colour":"red"}`
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   errdef.Code
+	}{
+		{"valid", order(), http.StatusOK, ""},
+		{"unknown field", withField(order()), http.StatusBadRequest, errdef.CodeInvalidInput},
+		{"trailing data", order() + ` {}`, http.StatusBadRequest, errdef.CodeInvalidInput},
+		{"current schema version", string(orderschema.Stamp([]byte(order()), orderschema.Current)), http.StatusOK, ""},
+		{"newer schema version", string(orderschema.Stamp([]byte(order()), orderschema.Current+1)), http.StatusBadRequest, errdef.CodeInvalidInput},
+		{"too large", `{"order_uid": "` + strings.Repeat("a", 4096) + `"}`, http.StatusRequestEntityTooLarge, errdef.CodeTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/order/", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.code == "" {
+				return
+			}
+			var problem errdef.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tt.code {
+				t.Fatalf("code %s, want %s: %s", problem.Code, tt.code, problem.Detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"order_service/internal/errdef"
+	"order_service/internal/export"
+	"order_service/internal/models"
+	"strconv"
+	"time"
+)
+
+// exportFlushEvery is how many orders are written between flushes, so clients see progress
+const exportFlushEvery = 1000
+
+// ExportOrders streams the orders matching the listing filters as NDJSON, CSV or Parquet.
+// Once the first order is written an error can only abort the response, so a client never
+// mistakes a truncated export for a complete one.
+func (h *OrderServiceHandler) ExportOrders(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	format := export.NDJSON
+	if v := q.Get("format"); v != "" {
+		var err error
+		if format, err = export.ParseFormat(v); err != nil {
+			return HttpError{err: err, msg: err.Error()}
+		}
+	}
+	filter, err := exportFilter(q.Get)
+	if err != nil {
+		return HttpError{err: err, msg: err.Error()}
+	}
+
+	var (
+		out     export.Writer
+		written int
+	)
+	rc := http.NewResponseController(w)
+	start := func() error {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="orders-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format.Extension()))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		out, err = export.NewWriter(format, w)
+		return err
+	}
+
+	err = h.service.ExportOrders(r.Context(), filter, func(o models.Order) error {
+		if out == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := out.Write(h.pii.Order(r.Context(), o)); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			rc.Flush()
+		}
+		return nil
+	})
+	if err == nil && out == nil {
+		//nothing matched, the client still gets a valid empty file
+		err = start()
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		if out == nil {
+			return HttpError{err: err}
+		}
+		h.logger.ErrorContext(r.Context(), "export failed after it started, aborting the response", "orders", written, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	h.logger.InfoContext(r.Context(), "orders exported", "format", format, "orders", written)
+	return nil
+}
+
+// exportFilter reads the listing filters, get returns "" for a missing value
+func exportFilter(get func(string) string) (models.OrderFilter, error) {
+	filter := models.OrderFilter{
+		CustomerID:      get("customer"),
+		DeliveryService: get("delivery_service"),
+	}
+	var err error
+	if v := get("min_amount"); v != "" {
+		if filter.MinAmount, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("%w: min_amount must be an integer", errdef.ErrInvalidInput)
+		}
+	}
+	if v := get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("%w: limit must be a non-negative integer", errdef.ErrInvalidInput)
+		}
+	}
+	if v := get("created_from"); v != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("%w: created_from must be an RFC 3339 time", errdef.ErrInvalidInput)
+		}
+	}
+	if v := get("created_to"); v != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("%w: created_to must be an RFC 3339 time", errdef.ErrInvalidInput)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/models"
+	"order_service/internal/pii"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/storage"
+	"strings"
+	"testing"
+)
+
+// brokenStream fails the export after the first order was streamed
+type brokenStream struct {
+	*storage.OrderStorageMemory
+}
+
+func (s brokenStream) StreamOrders(ctx context.Context, filter models.OrderFilter, fn func(models.Order) error) error {
+	first := true
+	return s.OrderStorageMemory.StreamOrders(ctx, filter, func(o models.Order) error {
+		if !first {
+			return errors.New("connection reset")
+		}
+		first = false
+		return fn(o)
+	})
+}
+
+// exportServer stores that many generated orders and serves them to an admin
+func exportServer(t *testing.T, orders int, opts ...testHandlerOption) *httptest.Server {
+	t.Helper()
+	h := newTestHandler(t, opts...)
+	h.saveOrders(t, orders)
+	masker := pii.NewMasker(pii.DefaultPolicy(), models.RoleAdmin)
+	srv := httptest.NewServer(h.WithPII(masker).SetRoutes())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExportEmptyResult(t *testing.T) {
+	srv := exportServer(t, 0)
+
+	resp, err := http.Get(srv.URL + "/orders/export?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if lines := strings.Split(strings.TrimSpace(string(body)), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], "order_uid,") {
+		t.Fatalf("an empty export should be the header only: %q", body)
+	}
+}
+
+// TestExportAbortsAfterStart checks that a failure mid-stream breaks the response instead of ending it cleanly
+func TestExportAbortsAfterStart(t *testing.T) {
+	srv := exportServer(t, 3, withStorage(func(st *storage.OrderStorageMemory) ports.OrderStorage { return brokenStream{st} }))
+
+	resp, err := http.Get(srv.URL + "/orders/export")
+	if err != nil {
+		//the abort came before the headers were flushed
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("a failed export ended like a complete one: status %d, %q", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"order_service/internal/generator"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/cache"
+	"order_service/internal/ports/adapters/storage"
+	"order_service/internal/service"
+	"testing"
+	"time"
+)
+
+// testHandler is a handler over memory storage and cache with a seeded order generator
+type testHandler struct {
+	*OrderServiceHandler
+	service *service.OrderService
+	storage *storage.OrderStorageMemory
+	gen     *generator.Generator
+}
+
+type testHandlerOption func(*testHandlerConfig)
+
+type testHandlerConfig struct {
+	storage func(*storage.OrderStorageMemory) ports.OrderStorage
+}
+
+// withStorage puts wrap between the service and the memory storage
+func withStorage(wrap func(*storage.OrderStorageMemory) ports.OrderStorage) testHandlerOption {
+	return func(c *testHandlerConfig) {
+		c.storage = wrap
+	}
+}
+
+// newTestHandler builds a handler without auth, rate limits or masking, the With* setters add them
+func newTestHandler(t testing.TB, opts ...testHandlerOption) *testHandler {
+	t.Helper()
+	cfg := testHandlerConfig{storage: func(st *storage.OrderStorageMemory) ports.OrderStorage { return st }}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	gen, err := generator.New(generator.Options{Seed: 1, MinItems: 1, MaxItems: 1, Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := storage.NewOrderStorageMemory()
+	svc := service.NewOrderService(cfg.storage(st), cache.NewOrderCacheMemory(time.Hour), logging.Nop())
+	return &testHandler{
+		OrderServiceHandler: NewOrderServiceHandler(svc, logging.Nop()),
+		service:             svc,
+		storage:             st,
+		gen:                 gen,
+	}
+}
+
+// saveOrders stores n generated orders directly in the storage
+func (h *testHandler) saveOrders(t testing.TB, n int) []models.Order {
+	t.Helper()
+	orders := make([]models.Order, 0, n)
+	for range n {
+		o := h.gen.Order()
+		if err := h.storage.SaveOrder(context.Background(), o); err != nil {
+			t.Fatal(err)
+		}
+		orders = append(orders, o)
+	}
+	return orders
+}
@@ -3,19 +3,49 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"order_service/internal/auth"
+	"order_service/internal/decoding"
+	"order_service/internal/errdef"
+	"order_service/internal/feed"
+	"order_service/internal/handler/openapi"
+	"order_service/internal/handler/web"
+	"order_service/internal/health"
+	"order_service/internal/httpcache"
+	"order_service/internal/logging"
+	"order_service/internal/metrics"
 	"order_service/internal/models"
-	"order_service/internal/ports/adapters/storage"
+	"order_service/internal/orderschema"
+	"order_service/internal/pii"
+	"order_service/internal/ratelimit"
 	"order_service/internal/service"
+	"order_service/internal/tracing"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi"
-	"github.com/go-chi/chi/middleware"
 )
 
 type OrderServiceHandler struct {
-	service *service.OrderService
+	service     *service.OrderService
+	health      *health.Checker
+	feed        *feed.Broker
+	auth        *auth.Authenticator
+	pii         *pii.Masker
+	limiter     *ratelimit.Middleware
+	cache       httpcache.CacheControl
+	compress    int
+	spec        *openapi3.T
+	decoder     decoding.Decoder
+	decodeOrder func([]byte) (models.Order, error)
+	heartbeat   time.Duration
+	logger      *slog.Logger
 }
 
+// HttpError carries the cause of a failed request. err is only logged, the client gets
+// the problem mapped from it by errdef.FromError; msg and Code override its detail and status.
 type HttpError struct {
 	Code int
 	err  error
@@ -26,28 +56,110 @@ func (he HttpError) Error() string {
 	return he.msg
 }
 
+func (he HttpError) Unwrap() error {
+	return he.err
+}
+
 type serviceHandle func(w http.ResponseWriter, r *http.Request) error
 
-func (fn serviceHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var httpErr HttpError
-	err := fn(w, r)
-	if err != nil {
-		if errors.As(err, &httpErr) {
-			http.Error(w, httpErr.msg, httpErr.Code)
-			return
-		} else {
-			http.Error(w, err.Error(), 500)
+// handle adapts fn to http, turning its error into a problem+json response
+func (h *OrderServiceHandler) handle(fn serviceHandle) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			h.writeError(w, r, err)
 		}
 	}
 }
-func (fn serviceHandle) HandlerFunc() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		fn.ServeHTTP(w, r)
+
+func (h *OrderServiceHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := errdef.FromError(err)
+
+	var httpErr HttpError
+	if errors.As(err, &httpErr) {
+		if httpErr.msg != "" {
+			problem.Detail = httpErr.msg
+		}
+		if httpErr.Code != 0 {
+			problem.Status = httpErr.Code
+			problem.Title = http.StatusText(httpErr.Code)
+		}
 	}
+
+	//the full error chain stays in the logs, the client only sees the problem
+	level := slog.LevelInfo
+	if problem.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	h.logger.Log(r.Context(), level, "request failed",
+		"status", problem.Status, "code", problem.Code, "error", err)
+
+	writeProblem(w, r, problem)
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, problem errdef.Problem) {
+	problem.Instance = r.URL.Path
+	problem.CorrelationID = logging.CorrelationID(r.Context())
+	problem.Write(w)
+}
+
+// NewOrderServiceHandler builds the http handlers of s, the With* setters add the rest.
+// Without them the API is served without authentication, rate limits or PII masking.
+func NewOrderServiceHandler(s *service.OrderService, logger *slog.Logger) *OrderServiceHandler {
+	return &OrderServiceHandler{service: s, logger: logger.With("component", "http"), decodeOrder: orderschema.Default(decoding.Decoder{}).Decode}
 }
 
-func NewOrderServiceHandler(s *service.OrderService) *OrderServiceHandler {
-	return &OrderServiceHandler{service: s}
+// WithHealth serves the probes of checker at /livez, /readyz and /health
+func (h *OrderServiceHandler) WithHealth(checker *health.Checker) *OrderServiceHandler {
+	h.health = checker
+	return h
+}
+
+// WithFeed serves broker's events at /orders/stream with a keep-alive comment every
+// heartbeat on idle streams, 0 disables them
+func (h *OrderServiceHandler) WithFeed(broker *feed.Broker, heartbeat time.Duration) *OrderServiceHandler {
+	h.feed = broker
+	h.heartbeat = heartbeat
+	return h
+}
+
+// WithAuth authenticates the order API with authn, nil serves it to everyone with full access
+func (h *OrderServiceHandler) WithAuth(authn *auth.Authenticator) *OrderServiceHandler {
+	h.auth = authn
+	return h
+}
+
+// WithPII masks the orders of callers below the masker's role, nil masks nothing
+func (h *OrderServiceHandler) WithPII(masker *pii.Masker) *OrderServiceHandler {
+	h.pii = masker
+	return h
+}
+
+// WithRateLimit limits the order API with limiter, nil disables rate limits
+func (h *OrderServiceHandler) WithRateLimit(limiter *ratelimit.Middleware) *OrderServiceHandler {
+	h.limiter = limiter
+	return h
+}
+
+// WithCaching sets the Cache-Control directives per route and the response compression level (0 disables it)
+func (h *OrderServiceHandler) WithCaching(cc httpcache.CacheControl, compressionLevel int) *OrderServiceHandler {
+	h.cache = cc
+	h.compress = compressionLevel
+	return h
+}
+
+// WithOpenAPI serves spec at /openapi.json with a docs page at /docs/ and validates
+// the order API's requests against it
+func (h *OrderServiceHandler) WithOpenAPI(spec *openapi3.T) *OrderServiceHandler {
+	h.spec = spec
+	return h
+}
+
+// WithDecoder sets the size limit and unknown field policy of request bodies, saved orders
+// are decoded with it through the orderschema registry like the recievers decode them
+func (h *OrderServiceHandler) WithDecoder(d decoding.Decoder) *OrderServiceHandler {
+	h.decoder = d
+	h.decodeOrder = orderschema.Default(d).Decode
+	return h
 }
 
 func (h *OrderServiceHandler) GetOrder(w http.ResponseWriter, r *http.Request) error {
@@ -55,39 +167,142 @@ func (h *OrderServiceHandler) GetOrder(w http.ResponseWriter, r *http.Request) e
 
 	order, err := h.service.GetOrder(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			return HttpError{err: err, Code: http.StatusNotFound, msg: "order not found"}
-
-		}
-		return HttpError{err: err, Code: http.StatusInternalServerError, msg: "falied to retrieve order" + err.Error()}
+		return HttpError{err: err}
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err = json.NewEncoder(w).Encode(order); err != nil {
-		return HttpError{err: err, Code: http.StatusInternalServerError, msg: "falied to write JSON" + err.Error()}
+	//the body is hashed for the ETag, so it is encoded up front
+	body, err := json.Marshal(h.pii.Order(r.Context(), order))
+	if err != nil {
+		return HttpError{err: fmt.Errorf("encode order: %w", err)}
 	}
+	//the masking depends on who asks, a cache must not hand one caller's copy to another
+	w.Header().Add("Vary", "Authorization, "+auth.APIKeyHeader)
+	//orders are immutable, the date they were created is when they were last modified
+	httpcache.Serve(w, r, "application/json", append(body, '\n'), order.DateCreated)
 	return nil
 }
 
 func (h *OrderServiceHandler) SaveOrder(w http.ResponseWriter, r *http.Request) error {
-	var order models.Order
-	err := json.NewDecoder(r.Body).Decode(&order)
+	b, err := h.decoder.Read(r.Body)
+	if err != nil {
+		return HttpError{err: err, msg: err.Error()}
+	}
+	//the body may name its schema_version like a kafka message, it is read the same way
+	order, err := h.decodeOrder(b)
 	if err != nil {
-		return HttpError{err: err, Code: http.StatusBadRequest, msg: "invalid input data" + err.Error()}
+		//decoder errors only describe the client's own payload, so they are safe to echo
+		return HttpError{err: err, msg: err.Error()}
 	}
 	err = h.service.SaveOrder(r.Context(), order)
 	if err != nil {
-		return HttpError{err: err, Code: http.StatusInternalServerError, msg: "cannot save order" + err.Error()}
+		return HttpError{err: err}
 	}
 	return nil
 }
 
+type batchGetRequest struct {
+	OrderUIDs []string `json:"order_uids"`
+}
+
+type batchGetResponse struct {
+	Orders  []models.Order `json:"orders"`
+	Missing []string       `json:"missing"`
+}
+
+// BatchGetOrders returns the requested orders that exist and the ids that don't
+func (h *OrderServiceHandler) BatchGetOrders(w http.ResponseWriter, r *http.Request) error {
+	var req batchGetRequest
+	if err := h.decoder.DecodeReader(r.Body, &req); err != nil {
+		return HttpError{err: err, msg: err.Error()}
+	}
+	if len(req.OrderUIDs) == 0 {
+		return HttpError{err: errdef.ErrInvalidInput, msg: "order_uids must not be empty"}
+	}
+
+	orders, missing, err := h.service.GetOrders(r.Context(), req.OrderUIDs)
+	if err != nil {
+		if errors.Is(err, errdef.ErrInvalidInput) {
+			return HttpError{err: err, msg: err.Error()}
+		}
+		return HttpError{err: err}
+	}
+
+	for i := range orders {
+		orders[i] = h.pii.Order(r.Context(), orders[i])
+	}
+	resp := batchGetResponse{Orders: orders, Missing: missing}
+	if resp.Orders == nil {
+		resp.Orders = []models.Order{}
+	}
+	if resp.Missing == nil {
+		resp.Missing = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to write JSON", "error", err)
+	}
+	return nil
+}
+
+// limitBody caps request bodies before anything reads them, the openapi validator included
+func (h *OrderServiceHandler) limitBody(next http.Handler) http.Handler {
+	limit := h.decoder.MaxBytes()
+	if limit <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(limit))
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (h *OrderServiceHandler) SetRoutes() http.Handler {
 	chi := chi.NewRouter()
-	chi.Use(middleware.Logger)
-	chi.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("working"))
+	chi.Use(tracing.Middleware)
+	chi.Use(logging.Middleware(h.logger))
+	chi.Use(metrics.Middleware)
+	chi.Use(h.cache.Middleware)
+	if h.compress > 0 {
+		chi.Use(httpcache.Compress(h.compress))
+	}
+	chi.Get("/metrics", metrics.Handler().ServeHTTP)
+	chi.Get("/livez", h.health.LivezHandler)
+	chi.Get("/readyz", h.health.ReadyzHandler)
+	//kept for old probes, same semantics as /livez
+	chi.Get("/health", h.health.LivezHandler)
+
+	//the order API carries customer PII, probes, metrics and the static UI stay public
+	//the IP limit comes first, failed authentications count against it too
+	api := chi.With(h.limiter.ByIP, auth.Middleware(h.auth, h.logger), h.limiter.Handler, h.limitBody)
+	if h.spec != nil {
+		api = api.With(openapi.NewValidator(h.spec, h.logger).Middleware)
+	}
+	api.With(auth.Require(models.RoleReader, h.logger)).Get("/order/{id}", h.handle(h.GetOrder))
+	api.With(auth.Require(models.RoleWriter, h.logger)).Post("/order/", h.handle(h.SaveOrder))
+	api.With(auth.Require(models.RoleReader, h.logger)).Get("/orders/stream", h.handle(h.StreamOrders))
+	api.With(auth.Require(models.RoleReader, h.logger)).Post("/orders:batchGet", h.handle(h.BatchGetOrders))
+	api.With(auth.Require(models.RoleReader, h.logger)).Get("/orders/export", h.handle(h.ExportOrders))
+
+	if h.spec != nil {
+		specHandler, err := openapi.Handler(h.spec)
+		if err != nil {
+			//the document was loaded from the same struct, it always encodes
+			panic(err)
+		}
+		chi.Get("/openapi.json", specHandler.ServeHTTP)
+		chi.Get("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently).ServeHTTP)
+		chi.Handle("/docs/*", http.StripPrefix("/docs/", openapi.Docs()))
+	}
+
+	//support UI for looking orders up by id
+	chi.Get("/", http.RedirectHandler("/ui/", http.StatusFound).ServeHTTP)
+	chi.Get("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently).ServeHTTP)
+	chi.Handle("/ui/*", http.StripPrefix("/ui/", web.Handler()))
+
+	chi.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, errdef.NewProblem(http.StatusNotFound, errdef.CodeRouteNotFound, "route not found"))
+	})
+	chi.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, errdef.NewProblem(http.StatusMethodNotAllowed, errdef.CodeMethodNotAllowed, "method not allowed"))
 	})
-	chi.Get("/order/{id}", serviceHandle(h.GetOrder).HandlerFunc())
-	chi.Post("/order/", serviceHandle(h.SaveOrder).HandlerFunc())
 	return chi
 }
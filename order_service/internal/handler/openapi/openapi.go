@@ -0,0 +1,226 @@
+package openapi
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"order_service/internal/errdef"
+	"order_service/internal/logging"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-chi/chi"
+)
+
+//go:embed openapi.yaml
+var document []byte
+
+//go:embed static
+var static embed.FS
+
+// Load parses and validates the embedded OpenAPI document
+func Load() (*openapi3.T, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(document)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi document: %w", err)
+	}
+	if err := spec.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+	return spec, nil
+}
+
+// Handler serves spec as JSON
+func Handler(spec *openapi3.T) (http.Handler, error) {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("encode openapi document: %w", err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}), nil
+}
+
+// Docs serves the API reference page, it renders /openapi.json without any external assets
+func Docs() http.Handler {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		//static is embedded at build time, a missing directory fails the build instead
+		panic(err)
+	}
+	return http.FileServer(http.FS(sub))
+}
+
+// Validator rejects requests whose parameters or body don't match the document
+type Validator struct {
+	spec   *openapi3.T
+	logger *slog.Logger
+}
+
+func NewValidator(spec *openapi3.T, logger *slog.Logger) *Validator {
+	return &Validator{spec: spec, logger: logger}
+}
+
+// Middleware must run after routing (inline with chi's With), it finds the operation by the
+// chi route pattern, which is written the same way as the document's paths.
+// Malformed input gets 400 invalid_input, a body breaking the schema 422 validation_failed.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		pattern := rctx.RoutePattern()
+		item := v.spec.Paths.Find(pattern)
+		var op *openapi3.Operation
+		if item != nil {
+			op = item.GetOperation(r.Method)
+		}
+		if op == nil {
+			//the drift test keeps this from happening, but a missing operation must not block the route
+			v.logger.WarnContext(r.Context(), "route is missing from the openapi document", "method", r.Method, "route", pattern)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		params := make(map[string]string, len(rctx.URLParams.Keys))
+		for i, key := range rctx.URLParams.Keys {
+			params[key] = rctx.URLParams.Values[i]
+		}
+		//clients have always been able to post JSON without a content type
+		if r.ContentLength != 0 && r.Header.Get("Content-Type") == "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route: &routers.Route{
+				Spec:      v.spec,
+				Path:      pattern,
+				PathItem:  item,
+				Method:    r.Method,
+				Operation: op,
+			},
+			Options: &openapi3filter.Options{
+				MultiError: true,
+				//auth.Middleware has already authenticated the request
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			v.reject(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *Validator) reject(w http.ResponseWriter, r *http.Request, err error) {
+	problem := toProblem(err)
+	v.logger.InfoContext(r.Context(), "request rejected by the openapi validator", "status", problem.Status, "error", err)
+
+	problem.Instance = r.URL.Path
+	problem.CorrelationID = logging.CorrelationID(r.Context())
+	problem.Write(w)
+}
+
+func toProblem(err error) errdef.Problem {
+	//Add caps the list, a body with thousands of violations gets a bounded response
+	var verr errdef.ValidationError
+	schemaViolation := false
+	for _, e := range flatten(err) {
+		var reqErr *openapi3filter.RequestError
+		var schemaErr *openapi3.SchemaError
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(e, &tooLarge):
+			//the handler's size limit cut the body off while it was read, that is not a schema problem
+			problem := errdef.FromError(errdef.ErrTooLarge)
+			problem.Detail = fmt.Sprintf("request body is over the %d byte limit", tooLarge.Limit)
+			return problem
+		case errors.As(e, &schemaErr) && errors.As(e, &reqErr) && reqErr.RequestBody != nil:
+			schemaViolation = true
+			verr.Add(fieldPath(schemaErr.JSONPointer()), schemaErr.Reason)
+		case errors.As(e, &reqErr) && reqErr.Parameter != nil:
+			verr.Add(reqErr.Parameter.Name, paramReason(reqErr))
+		case errors.As(e, &reqErr):
+			verr.Add("body", reqErr.Reason)
+		default:
+			verr.Add("request", e.Error())
+		}
+	}
+
+	if schemaViolation {
+		problem := errdef.FromError(&verr)
+		problem.Detail = "request body does not match the API schema"
+		return problem
+	}
+	problem := errdef.FromError(errdef.ErrInvalidInput)
+	problem.Errors = verr.Fields
+	return problem
+}
+
+// flatten unpacks the multi errors ValidateRequest returns with Options.MultiError,
+// keeping the RequestError around every schema error so its origin (body or parameter) is known
+func flatten(err error) []error {
+	var multi openapi3.MultiError
+	if !errors.As(err, &multi) {
+		return []error{err}
+	}
+	var out []error
+	for _, e := range multi {
+		var reqErr *openapi3filter.RequestError
+		var inner openapi3.MultiError
+		if errors.As(e, &reqErr) && errors.As(reqErr.Err, &inner) {
+			for _, ie := range inner {
+				copied := *reqErr
+				copied.Err = ie
+				out = append(out, &copied)
+			}
+			continue
+		}
+		out = append(out, flatten(e)...)
+	}
+	return out
+}
+
+func paramReason(reqErr *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	if reqErr.Reason != "" {
+		return reqErr.Reason
+	}
+	return reqErr.Err.Error()
+}
+
+// fieldPath writes a JSON pointer like order.Validate names fields: items[0].name
+func fieldPath(pointer []string) string {
+	var b strings.Builder
+	for _, p := range pointer {
+		if _, err := strconv.Atoi(p); err == nil {
+			b.WriteString("[" + p + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(p)
+	}
+	if b.Len() == 0 {
+		return "body"
+	}
+	return b.String()
+}
@@ -0,0 +1,198 @@
+"use strict";
+
+(function () {
+  const status = document.getElementById("status");
+  let spec;
+
+  function el(tag, attrs, children) {
+    const node = document.createElement(tag);
+    for (const [k, v] of Object.entries(attrs || {})) {
+      if (k === "text") {
+        node.textContent = v;
+      } else {
+        node.setAttribute(k, v);
+      }
+    }
+    for (const child of children || []) {
+      if (child) {
+        node.appendChild(child);
+      }
+    }
+    return node;
+  }
+
+  function resolve(obj) {
+    if (!obj || !obj.$ref) {
+      return obj;
+    }
+    // only local references are used: #/components/...
+    return obj.$ref.slice(2).split("/").reduce((o, key) => o[key], spec);
+  }
+
+  function refName(obj) {
+    return obj && obj.$ref ? obj.$ref.split("/").pop() : "";
+  }
+
+  function typeOf(schema) {
+    const name = refName(schema);
+    if (name) {
+      return el("a", { href: "#schema-" + name, text: name });
+    }
+    schema = schema || {};
+    let text = schema.type || "any";
+    if (schema.type === "array") {
+      const inner = typeOf(schema.items);
+      const span = el("span", {}, [document.createTextNode("array of ")]);
+      span.appendChild(inner);
+      return span;
+    }
+    if (schema.format) {
+      text += " (" + schema.format + ")";
+    }
+    if (schema.enum) {
+      text += ": " + schema.enum.join(" | ");
+    }
+    return el("code", { text: text });
+  }
+
+  function schemaList(schema) {
+    schema = resolve(schema) || {};
+    if (!schema.properties) {
+      return el("p", {}, [typeOf(schema)]);
+    }
+    const required = new Set(schema.required || []);
+    const ul = el("ul", { class: "schema" });
+    for (const [name, prop] of Object.entries(schema.properties)) {
+      const li = el("li", {}, [
+        el("code", { text: name }),
+        required.has(name) ? el("span", { class: "req", text: " *" }) : null,
+        document.createTextNode(" "),
+        typeOf(prop),
+      ]);
+      const desc = (resolve(prop) || {}).description;
+      if (desc && !prop.$ref) {
+        li.appendChild(el("span", { class: "muted", text: " — " + desc }));
+      }
+      ul.appendChild(li);
+    }
+    return ul;
+  }
+
+  function operation(path, method, op) {
+    const body = el("div");
+    if (op.description) {
+      body.appendChild(el("p", { text: op.description }));
+    }
+    const security = op.security || spec.security || [];
+    body.appendChild(el("p", { class: "muted", text: security.length ? "Needs credentials: " + security.map((s) => Object.keys(s)[0]).join(" or ") : "Public" }));
+
+    if (op.parameters && op.parameters.length) {
+      body.appendChild(el("h4", { text: "Parameters" }));
+      const table = el("table", {}, [el("tr", {}, [el("th", { text: "Name" }), el("th", { text: "In" }), el("th", { text: "Type" }), el("th", { text: "Description" })])]);
+      for (const p of op.parameters.map(resolve)) {
+        table.appendChild(el("tr", {}, [
+          el("td", {}, [el("code", { text: p.name }), p.required ? el("span", { class: "req", text: " *" }) : null]),
+          el("td", { text: p.in }),
+          el("td", {}, [typeOf(p.schema)]),
+          el("td", { text: p.description || "" }),
+        ]));
+      }
+      body.appendChild(table);
+    }
+
+    if (op.requestBody) {
+      const rb = resolve(op.requestBody);
+      for (const [type, media] of Object.entries(rb.content || {})) {
+        body.appendChild(el("h4", { text: "Request body (" + type + ")" }));
+        body.appendChild(schemaList(media.schema));
+      }
+    }
+
+    body.appendChild(el("h4", { text: "Responses" }));
+    const table = el("table");
+    for (const [code, raw] of Object.entries(op.responses || {})) {
+      const resp = resolve(raw);
+      const types = Object.entries(resp.content || {}).map(([type, media]) => {
+        const span = el("span", {}, [document.createTextNode(type + " ")]);
+        if (media.schema) {
+          span.appendChild(typeOf(media.schema));
+        }
+        return span;
+      });
+      table.appendChild(el("tr", {}, [el("td", {}, [el("code", { text: code })]), el("td", { text: resp.description || "" }), el("td", {}, types)]));
+    }
+    body.appendChild(table);
+
+    return el("details", { id: op.operationId || "" }, [
+      el("summary", {}, [
+        el("span", { class: "method " + method, text: method.toUpperCase() }),
+        el("code", { class: op.deprecated ? "deprecated" : "", text: path }),
+        el("span", { class: "muted", text: op.summary || "" }),
+      ]),
+      body,
+    ]);
+  }
+
+  function render() {
+    document.getElementById("title").textContent = spec.info.title;
+    document.getElementById("version").textContent = "v" + spec.info.version;
+    document.title = spec.info.title + " API";
+    for (const para of (spec.info.description || "").split("\n\n")) {
+      document.getElementById("description").appendChild(el("p", { text: para }));
+    }
+
+    const byTag = new Map();
+    for (const [path, item] of Object.entries(spec.paths)) {
+      for (const method of ["get", "put", "post", "patch", "delete"]) {
+        const op = item[method];
+        if (!op) {
+          continue;
+        }
+        const tag = (op.tags || ["default"])[0];
+        if (!byTag.has(tag)) {
+          byTag.set(tag, []);
+        }
+        byTag.get(tag).push(operation(path, method, op));
+      }
+    }
+    const operations = document.getElementById("operations");
+    for (const [tag, ops] of byTag) {
+      operations.appendChild(el("h2", { text: tag }));
+      ops.forEach((op) => operations.appendChild(op));
+    }
+
+    const schemas = document.getElementById("schemas");
+    for (const [name, schema] of Object.entries((spec.components || {}).schemas || {})) {
+      schemas.appendChild(el("details", { id: "schema-" + name }, [
+        el("summary", {}, [el("h3", { text: name }), el("span", { class: "muted", text: schema.description || "" })]),
+        el("div", {}, [schemaList(schema)]),
+      ]));
+    }
+    document.getElementById("schemas-title").classList.remove("hidden");
+    status.classList.add("hidden");
+
+    // links to a schema open it
+    window.addEventListener("hashchange", () => {
+      const target = document.getElementById(location.hash.slice(1));
+      if (target && target.tagName === "DETAILS") {
+        target.open = true;
+      }
+    });
+  }
+
+  fetch("../openapi.json")
+    .then((resp) => {
+      if (!resp.ok) {
+        throw new Error("status " + resp.status);
+      }
+      return resp.json();
+    })
+    .then((doc) => {
+      spec = doc;
+      render();
+    })
+    .catch((e) => {
+      status.textContent = "Cannot load the API document: " + e.message;
+      status.classList.add("error");
+    });
+})();
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/errdef"
+	"order_service/internal/handler/openapi"
+	"order_service/internal/logging"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+// undocumented are the routes serving static pages, they are not part of the API
+var undocumented = []string{"/", "/ui", "/ui/*", "/docs", "/docs/*"}
+
+func testRouter(t *testing.T) http.Handler {
+	t.Helper()
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewOrderServiceHandler(nil, logging.Nop()).WithOpenAPI(spec)
+	return h.SetRoutes()
+}
+
+// TestOpenAPIMatchesRoutes fails when a route is added to SetRoutes without documenting it,
+// or the document describes an operation that is not served
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := map[string]bool{}
+	err = chi.Walk(testRouter(t).(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		for _, skip := range undocumented {
+			if route == skip {
+				return nil
+			}
+		}
+		served[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documented := map[string]bool{}
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			documented[method+" "+path] = true
+		}
+	}
+
+	for _, op := range sorted(served) {
+		if !documented[op] {
+			t.Errorf("%s is served but missing from openapi.yaml", op)
+		}
+	}
+	for _, op := range sorted(documented) {
+		if !served[op] {
+			t.Errorf("%s is documented in openapi.yaml but not served", op)
+		}
+	}
+}
+
+func sorted(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestOpenAPIValidatorRejectsBadBodies(t *testing.T) {
+	srv := httptest.NewServer(testRouter(t))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		code   errdef.Code
+		field  string
+	}{
+		{"malformed json", "/order/", `{"order_uid":`, http.StatusBadRequest, errdef.CodeInvalidInput, "body"},
+		{"wrong type", "/order/", `{"order_uid": 42}`, http.StatusUnprocessableEntity, errdef.CodeValidationFailed, "order_uid"},
+		{"missing field", "/order/", `{"order_uid": "x"}`, http.StatusUnprocessableEntity, errdef.CodeValidationFailed, "track_number"},
+		{"empty batch", "/orders:batchGet", `{"order_uids": []}`, http.StatusUnprocessableEntity, errdef.CodeValidationFailed, "order_uids"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var problem errdef.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || problem.Code != tt.code {
+				t.Fatalf("got %d %s, want %d %s", resp.StatusCode, problem.Code, tt.status, tt.code)
+			}
+			for _, f := range problem.Errors {
+				if f.Field == tt.field {
+					return
+				}
+			}
+			t.Errorf("no error for field %q in %+v", tt.field, problem.Errors)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestOrdersWithoutMasker checks a handler built without WithPII serves orders unmasked
+func TestOrdersWithoutMasker(t *testing.T) {
+	h := newTestHandler(t)
+	o := h.saveOrders(t, 1)[0]
+	srv := httptest.NewServer(h.SetRoutes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/order/" + o.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got struct {
+		Delivery struct {
+			Phone string `json:"phone"`
+		} `json:"delivery"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET: status %d, err %v", resp.StatusCode, err)
+	}
+	if got.Delivery.Phone != o.Delivery.Phone {
+		t.Fatalf("phone %q, want it unmasked %q", got.Delivery.Phone, o.Delivery.Phone)
+	}
+
+	batch, err := http.Post(srv.URL+"/orders:batchGet", "application/json", strings.NewReader(`{"order_uids": ["`+o.OrderUID+`"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch.Body.Close()
+	if batch.StatusCode != http.StatusOK {
+		t.Fatalf("batchGet: status %d", batch.StatusCode)
+	}
+}
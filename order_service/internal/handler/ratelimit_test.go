@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/auth"
+	"order_service/internal/errdef"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"order_service/internal/ratelimit"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingKeyStore knows no keys and counts the lookups
+type countingKeyStore struct {
+	lookups atomic.Int64
+}
+
+func (s *countingKeyStore) GetByHash(context.Context, string) (models.APIKey, error) {
+	s.lookups.Add(1)
+	return models.APIKey{}, errdef.ErrNotFound
+}
+func (s *countingKeyStore) Create(context.Context, models.APIKey) error   { return nil }
+func (s *countingKeyStore) List(context.Context) ([]models.APIKey, error) { return nil, nil }
+func (s *countingKeyStore) Revoke(context.Context, string) error          { return nil }
+
+// TestBogusKeysAreRateLimited checks that the IP limit runs before authentication
+func TestBogusKeysAreRateLimited(t *testing.T) {
+	keys := &countingKeyStore{}
+	authn := auth.NewAuthenticator(keys, "", time.Minute)
+	routes, err := ratelimit.ParseRoutes(ratelimit.Limit{Rate: 100, Burst: 100}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := ratelimit.NewMiddleware(ratelimit.NewMemory(), routes, false, logging.Nop()).
+		WithIPLimit(ratelimit.Limit{Rate: 0.001, Burst: 3})
+	srv := httptest.NewServer(newTestHandler(t).WithAuth(authn).WithRateLimit(limiter).SetRoutes())
+	defer srv.Close()
+
+	var statuses []int
+	for i := range 5 {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/order/x", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-API-Key", fmt.Sprintf("%sbogus%d", auth.KeyPrefix, i))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+
+	want := []int{401, 401, 401, 429, 429}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Fatalf("statuses %v, want %v", statuses, want)
+	}
+	if n := keys.lookups.Load(); n != 3 {
+		t.Fatalf("%d key store lookups, want 3", n)
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/decoding"
+	"order_service/internal/decoding/decodingtest"
+	"order_service/internal/models"
+	"runtime"
+	"testing"
+)
+
+// FuzzSaveOrder posts untrusted bodies to POST /order/. The handler must not panic or
+// answer 5xx, memory must stay within what decoding the body may take, and an accepted
+// order must be stored in a form that round-trips through the cache encoding.
+func FuzzSaveOrder(f *testing.F) {
+	decodingtest.AddSeeds(f)
+	decoder, err := decoding.New(1<<20, decoding.Record, "http")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		h := newTestHandler(t)
+		h.service.WithValidation(true)
+		st := h.storage
+		routes := h.WithDecoder(decoder).SetRoutes()
+
+		req := httptest.NewRequest(http.MethodPost, "/order/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		routes.ServeHTTP(rec, req)
+		runtime.ReadMemStats(&after)
+
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > decodingtest.AllocBound(len(body), decoder.MaxBytes()) {
+			t.Fatalf("a %d byte body allocated %d bytes", len(body), alloc)
+		}
+
+		switch rec.Code {
+		case http.StatusOK:
+		case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+			if st.Len() != 0 {
+				t.Fatalf("status %d, but the order was stored", rec.Code)
+			}
+			return
+		default:
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+
+		orders, err := st.ListOrders(context.Background(), models.OrderFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orders) != 1 {
+			t.Fatalf("accepted body stored %d orders", len(orders))
+		}
+		decodingtest.AssertRoundTrip(t, orders[0])
+	})
+}
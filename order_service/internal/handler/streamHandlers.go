@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"order_service/internal/errdef"
+	"order_service/internal/feed"
+	"strconv"
+	"time"
+)
+
+// StreamOrders is a Server-Sent Events feed of saved orders.
+// Query params customer, delivery_service and min_amount filter it server-side,
+// Last-Event-ID (header or last_event_id param) resumes from the in-memory buffer.
+func (h *OrderServiceHandler) StreamOrders(w http.ResponseWriter, r *http.Request) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return HttpError{err: fmt.Errorf("%T does not support flushing", w), msg: "streaming is not supported"}
+	}
+
+	q := r.URL.Query()
+	filter := feed.Filter{
+		CustomerID:      q.Get("customer"),
+		DeliveryService: q.Get("delivery_service"),
+	}
+	if v := q.Get("min_amount"); v != "" {
+		amount, err := strconv.Atoi(v)
+		if err != nil {
+			return HttpError{err: fmt.Errorf("%w: min_amount: %w", errdef.ErrInvalidInput, err), msg: "min_amount must be an integer"}
+		}
+		filter.MinAmount = amount
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("last_event_id")
+	}
+	var last uint64
+	if lastID != "" {
+		var err error
+		if last, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			return HttpError{err: fmt.Errorf("%w: last event id: %w", errdef.ErrInvalidInput, err), msg: "Last-Event-ID must be a positive integer"}
+		}
+	}
+
+	sub, replay, complete := h.feed.Subscribe(filter, last)
+	defer h.feed.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	//disables response buffering in nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	//tell the client to reconnect after 3s and that some events could not be replayed
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: gap\ndata: {\"reason\":\"events after Last-Event-ID were evicted from the buffer\"}\n\n")
+	}
+	for _, ev := range replay {
+		if err := h.writeEvent(r.Context(), w, ev); err != nil {
+			return nil
+		}
+	}
+	flusher.Flush()
+
+	//FEED_HEARTBEAT=0 turns heartbeats off, a nil channel never fires
+	var heartbeat <-chan time.Time
+	if h.heartbeat > 0 {
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case ev, ok := <-sub.C:
+			if !ok {
+				if h.feed.Dropped(sub) {
+					h.logger.WarnContext(r.Context(), "slow SSE client dropped", "remote_addr", r.RemoteAddr)
+					fmt.Fprint(w, "event: dropped\ndata: {\"reason\":\"client too slow\"}\n\n")
+					flusher.Flush()
+				}
+				return nil
+			}
+			if err := h.writeEvent(r.Context(), w, ev); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-heartbeat:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *OrderServiceHandler) writeEvent(ctx context.Context, w http.ResponseWriter, ev feed.Event) error {
+	data, err := json.Marshal(h.pii.Summary(ctx, ev.Summary))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", ev.ID, data)
+	return err
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"order_service/internal/feed"
+	"order_service/internal/models"
+	"order_service/internal/pii"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStreamWithoutHeartbeat checks that FEED_HEARTBEAT=0 turns heartbeats off instead of breaking the stream
+func TestStreamWithoutHeartbeat(t *testing.T) {
+	broker := feed.NewBroker(10, 10)
+	h := newTestHandler(t)
+	h.service.WithNotifier(broker)
+	masker := pii.NewMasker(pii.DefaultPolicy(), models.RoleAdmin)
+	srv := httptest.NewServer(h.WithFeed(broker, 0).WithPII(masker).SetRoutes())
+	defer srv.Close()
+	defer broker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/orders/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	o := h.gen.Order()
+	if err := h.service.SaveOrder(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		if strings.HasPrefix(lines.Text(), "data: ") && strings.Contains(lines.Text(), o.OrderUID) {
+			return
+		}
+	}
+	t.Fatalf("the saved order was not streamed: %v", lines.Err())
+}
@@ -0,0 +1,167 @@
+"use strict";
+
+(function () {
+  const form = document.getElementById("search");
+  const input = document.getElementById("order-uid");
+  const button = form.querySelector("button");
+  const status = document.getElementById("status");
+  const orderSection = document.getElementById("order");
+  const apiKey = document.getElementById("api-key");
+
+  // the key stays in this browser only, it is sent as X-API-Key with every lookup
+  apiKey.value = localStorage.getItem("apiKey") || "";
+  apiKey.addEventListener("change", function () {
+    localStorage.setItem("apiKey", apiKey.value.trim());
+  });
+
+  function setText(id, value) {
+    document.getElementById(id).textContent = value === undefined || value === null || value === "" ? "—" : String(value);
+  }
+
+  function money(amount, currency) {
+    if (typeof amount !== "number") {
+      return "—";
+    }
+    try {
+      return new Intl.NumberFormat(undefined, { style: "currency", currency: currency || "USD" }).format(amount);
+    } catch (e) {
+      // unknown currency code, fall back to a plain number
+      return amount.toLocaleString() + " " + (currency || "");
+    }
+  }
+
+  function date(value) {
+    const d = new Date(value);
+    return isNaN(d.getTime()) ? value : d.toLocaleString();
+  }
+
+  function showStatus(message, isError) {
+    status.textContent = message;
+    status.classList.toggle("error", Boolean(isError));
+    status.classList.remove("hidden");
+  }
+
+  function hideStatus() {
+    status.classList.add("hidden");
+  }
+
+  function render(order) {
+    const p = order.payment || {};
+    const d = order.delivery || {};
+    const items = order.items || [];
+
+    setText("o-uid", order.order_uid);
+    setText("o-track", order.track_number);
+    setText("o-entry", order.entry);
+    setText("o-customer", order.customer_id);
+    setText("o-delivery-service", order.delivery_service);
+    setText("o-locale", order.locale);
+    setText("o-created", date(order.date_created));
+    setText("o-shard", order.shardkey + " / " + order.oof_shard);
+
+    setText("d-name", d.name);
+    setText("d-phone", d.phone);
+    setText("d-email", d.email);
+    setText("d-address", [d.zip, d.region, d.city, d.address].filter(Boolean).join(", "));
+
+    setText("p-transaction", p.transaction);
+    setText("p-provider", [p.provider, p.bank].filter(Boolean).join(" / "));
+    setText("p-dt", p.payment_dt ? new Date(p.payment_dt * 1000).toLocaleString() : "");
+    setText("p-goods", money(p.goods_total, p.currency));
+    setText("p-delivery", money(p.delivery_cost, p.currency));
+    setText("p-fee", money(p.custom_fee, p.currency));
+    setText("p-amount", money(p.amount, p.currency));
+
+    const tbody = document.getElementById("items");
+    tbody.replaceChildren();
+    let priceSum = 0;
+    let totalSum = 0;
+    for (const it of items) {
+      const tr = document.createElement("tr");
+      const cells = [
+        [it.chrt_id], [it.name], [it.brand], [it.size],
+        [money(it.price, p.currency), "num"], [it.sale + "%", "num"],
+        [money(it.total_price, p.currency), "num"], [it.status],
+      ];
+      for (const [value, cls] of cells) {
+        const td = document.createElement("td");
+        td.textContent = value === undefined || value === null ? "" : String(value);
+        if (cls) {
+          td.className = cls;
+        }
+        tr.appendChild(td);
+      }
+      tbody.appendChild(tr);
+      priceSum += it.price || 0;
+      totalSum += it.total_price || 0;
+    }
+    setText("items-count", items.length + (items.length === 1 ? " item" : " items"));
+    setText("items-price", money(priceSum, p.currency));
+    setText("items-total", money(totalSum, p.currency));
+
+    orderSection.classList.remove("hidden");
+  }
+
+  async function describeError(resp) {
+    if (resp.status === 404) {
+      return "Order not found.";
+    }
+    if (resp.status === 401) {
+      return "Enter a valid API key to look orders up.";
+    }
+    if (resp.status === 403) {
+      return "This API key is not allowed to read orders.";
+    }
+    // the API answers with application/problem+json, show its detail when there is one
+    try {
+      const problem = await resp.json();
+      if (problem && problem.detail) {
+        return problem.title + ": " + problem.detail + (problem.correlation_id ? " (request id " + problem.correlation_id + ")" : "");
+      }
+    } catch (e) {
+      // not JSON, fall through
+    }
+    return "Request failed with status " + resp.status + ".";
+  }
+
+  async function lookup(id) {
+    orderSection.classList.add("hidden");
+    showStatus("Loading…");
+    button.disabled = true;
+    try {
+      const headers = { Accept: "application/json" };
+      const key = apiKey.value.trim();
+      if (key) {
+        headers["X-API-Key"] = key;
+      }
+      const resp = await fetch("../order/" + encodeURIComponent(id), { headers: headers });
+      if (!resp.ok) {
+        showStatus(await describeError(resp), resp.status !== 404);
+        return;
+      }
+      render(await resp.json());
+      hideStatus();
+    } catch (e) {
+      showStatus("Cannot reach the order service: " + e.message, true);
+    } finally {
+      button.disabled = false;
+    }
+  }
+
+  form.addEventListener("submit", function (ev) {
+    ev.preventDefault();
+    const id = input.value.trim();
+    if (!id) {
+      return;
+    }
+    // keep the id in the url so a lookup can be shared or reloaded
+    history.replaceState(null, "", "?id=" + encodeURIComponent(id));
+    lookup(id);
+  });
+
+  const initial = new URLSearchParams(location.search).get("id");
+  if (initial) {
+    input.value = initial;
+    lookup(initial);
+  }
+})();
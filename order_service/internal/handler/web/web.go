@@ -0,0 +1,21 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the order lookup UI. Everything it needs is embedded into the binary,
+// so it works without internet access or a CDN.
+func Handler() http.Handler {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		//the embed pattern above guarantees the directory exists
+		panic(err)
+	}
+	return http.FileServer(http.FS(sub))
+}
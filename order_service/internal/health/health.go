@@ -0,0 +1,168 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckFunc probes a single dependency, it should respect ctx deadline
+type CheckFunc func(ctx context.Context) error
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+type CheckResult struct {
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status Status                 `json:"status"`
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+
+	mu     sync.Mutex
+	last   CheckResult
+	hasRun bool
+}
+
+// Checker runs dependency checks and caches their results for cacheTTL,
+// so frequent probes from the orchestrator don't hammer the dependencies
+type Checker struct {
+	checks   []*check
+	cacheTTL time.Duration
+	ready    atomic.Bool
+}
+
+func NewChecker(cacheTTL time.Duration) *Checker {
+	return &Checker{cacheTTL: cacheTTL}
+}
+
+// Register adds a dependency check, it must be called before the checker is used
+func (c *Checker) Register(name string, timeout time.Duration, fn CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, timeout: timeout, fn: fn})
+}
+
+// SetReady flips the readiness gate, it is false during startup warm-up and shutdown
+func (c *Checker) SetReady(ready bool) {
+	c.ready.Store(ready)
+}
+
+// Check runs every registered check concurrently, reusing cached results that are still fresh
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = ch.run(ctx, c.cacheTTL)
+		}()
+	}
+	wg.Wait()
+
+	return c.report(results)
+}
+
+// Cached returns the last known results without probing the dependencies
+func (c *Checker) Cached() Report {
+	results := make([]CheckResult, len(c.checks))
+	for i, ch := range c.checks {
+		ch.mu.Lock()
+		results[i] = ch.last
+		if !ch.hasRun {
+			results[i] = CheckResult{Status: StatusFail, Error: "not checked yet"}
+		}
+		ch.mu.Unlock()
+	}
+	return c.report(results)
+}
+
+func (c *Checker) report(results []CheckResult) Report {
+	rep := Report{Status: StatusOK, Ready: c.ready.Load(), Checks: make(map[string]CheckResult, len(results))}
+	for i, res := range results {
+		rep.Checks[c.checks[i].name] = res
+		if res.Status != StatusOK {
+			rep.Status = StatusFail
+		}
+	}
+	return rep
+}
+
+func (ch *check) run(ctx context.Context, ttl time.Duration) CheckResult {
+	//holding the lock while probing collapses concurrent probes into one
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.hasRun && time.Since(ch.last.CheckedAt) < ttl {
+		return ch.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ch.safeCall(ctx)
+	res := CheckResult{Status: StatusOK, Duration: time.Since(start).String(), CheckedAt: start}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+
+	ch.last = res
+	ch.hasRun = true
+	return res
+}
+
+func (ch *check) safeCall(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+	return ch.fn(ctx)
+}
+
+// LivezHandler reports that the process is alive. Dependency results are included
+// for visibility, but never fail the probe: restarting the service does not fix a
+// postgres outage and would only turn it into a restart loop.
+func (c *Checker) LivezHandler(w http.ResponseWriter, r *http.Request) {
+	rep := c.Cached()
+	rep.Status = StatusOK
+	writeReport(w, http.StatusOK, rep)
+}
+
+// ReadyzHandler fails when the service is warming up or shutting down or any dependency check fails
+func (c *Checker) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	rep := c.Check(r.Context())
+	code := http.StatusOK
+	if !rep.Ready || rep.Status != StatusOK {
+		rep.Status = StatusFail
+		code = http.StatusServiceUnavailable
+	}
+	writeReport(w, code, rep)
+}
+
+func writeReport(w http.ResponseWriter, code int, rep Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,186 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+// ETag is a strong validator for body, the hash of its bytes
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Serve writes body with an ETag and, when modified is not zero, a Last-Modified header,
+// or just 304 Not Modified when the request's If-None-Match/If-Modified-Since still match.
+func Serve(w http.ResponseWriter, r *http.Request, contentType string, body []byte, modified time.Time) {
+	etag := ETag(body)
+	h := w.Header()
+	h.Set("ETag", etag)
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, modified, h) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// notModified evaluates the conditional headers like RFC 9110 13.2.2: If-None-Match wins,
+// If-Modified-Since is only looked at when there is none
+func notModified(r *http.Request, etag string, modified time.Time, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" {
+				return true
+			}
+			if sameTag(tag, etag) {
+				//answer with the tag the client has, it may carry the content coding suffix
+				h.Set("ETag", strings.TrimPrefix(tag, "W/"))
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		//Last-Modified has second precision
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// sameTag is the weak comparison If-None-Match uses, ignoring the -gzip/-br suffix
+// the tag got when the response was compressed
+func sameTag(candidate, etag string) bool {
+	candidate = strings.TrimPrefix(candidate, "W/")
+	for _, enc := range []string{"gzip", "br"} {
+		if strings.HasSuffix(candidate, "-"+enc+`"`) {
+			candidate = strings.TrimSuffix(candidate, "-"+enc+`"`) + `"`
+			break
+		}
+	}
+	return candidate == etag
+}
+
+// CacheControl holds the Cache-Control directives of routes, keyed by "METHOD pattern"
+type CacheControl map[string]string
+
+// ParseCacheControl reads "GET /order/{id}=private, max-age=60;GET /ui/*=public, max-age=300"
+func ParseCacheControl(s string) (CacheControl, error) {
+	cc := make(CacheControl)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, directives, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(directives) == "" {
+			return nil, fmt.Errorf("cache control: %q is not \"METHOD /route=directives\"", entry)
+		}
+		cc[strings.Join(strings.Fields(route), " ")] = strings.TrimSpace(directives)
+	}
+	return cc, nil
+}
+
+// Middleware sets the route's Cache-Control on successful responses that don't set their own
+// (errors are never cached), and suffixes strong ETags with the content coding of compressed
+// responses so each coding has its own tag. It must run outside of the compressor.
+func (cc CacheControl) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&writer{ResponseWriter: w, r: r, cc: cc}, r)
+	})
+}
+
+type writer struct {
+	http.ResponseWriter
+	r           *http.Request
+	cc          CacheControl
+	wroteHeader bool
+}
+
+func (w *writer) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.setHeaders(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) setHeaders(code int) {
+	h := w.Header()
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		if enc := h.Get("Content-Encoding"); enc != "" {
+			h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+enc+`"`)
+		}
+	}
+
+	if h.Get("Cache-Control") != "" || (code >= 300 && code != http.StatusNotModified) {
+		return
+	}
+	pattern := ""
+	if rctx := chi.RouteContext(w.r.Context()); rctx != nil {
+		pattern = rctx.RoutePattern()
+	}
+	if directives, ok := w.cc[w.r.Method+" "+pattern]; ok {
+		h.Set("Cache-Control", directives)
+	}
+}
+
+// Flush keeps SSE streaming through the wrapper
+func (w *writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible are the content types worth compressing, SSE is left out so events are not buffered
+var compressible = []string{
+	"application/json",
+	"application/problem+json",
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/csv",
+	"application/x-ndjson",
+	"text/javascript",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// Compress negotiates br or gzip by Accept-Encoding, preferring br. level is used for both,
+// gzip takes 1-9 and brotli 0-11.
+func Compress(level int) func(http.Handler) http.Handler {
+	c := middleware.NewCompressor(level, compressible...)
+	c.SetEncoder("br", func(w io.Writer, level int) io.Writer {
+		return brotli.NewWriterLevel(w, level)
+	})
+	return c.Handler
+}
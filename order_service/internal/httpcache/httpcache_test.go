@@ -0,0 +1,63 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServeConditional(t *testing.T) {
+	body := []byte(`{"order_uid": "x"}`)
+	etag := ETag(body)
+	modified := time.Date(2026, 1, 1, 12, 0, 0, 500, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	at := modified.Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"unconditional", nil, http.StatusOK},
+		{"matching tag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"tag of a compressed copy", map[string]string{"If-None-Match": `W/` + etag[:len(etag)-1] + `-gzip"`}, http.StatusNotModified},
+		{"other tag", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"unmodified since", map[string]string{"If-Modified-Since": at}, http.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": before}, http.StatusOK},
+		{"malformed date", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		//RFC 9110 13.2.2: If-Modified-Since is ignored when If-None-Match is present
+		{"other tag wins over the date", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": at}, http.StatusOK},
+		{"matching tag wins over the date", map[string]string{"If-None-Match": etag, "If-Modified-Since": before}, http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			Serve(rec, req, "application/json", body, modified)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if lm := rec.Header().Get("Last-Modified"); lm != at {
+				t.Fatalf("Last-Modified %q, want %q", lm, at)
+			}
+			if tt.status == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Fatalf("304 with a body: %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestServeWithoutModified(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+	req.Header.Set("If-Modified-Since", time.Now().Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	Serve(rec, req, "application/json", []byte(`{}`), time.Time{})
+	if rec.Code != http.StatusOK || rec.Header().Get("Last-Modified") != "" {
+		t.Fatalf("status %d, Last-Modified %q: want 200 without one", rec.Code, rec.Header().Get("Last-Modified"))
+	}
+}
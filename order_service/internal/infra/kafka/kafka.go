@@ -1,10 +1,12 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"order_service/internal/config"
 	"strconv"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -52,10 +54,105 @@ func CreateTopicIfNotExists(conf config.KafkaConfig) error {
 	return nil
 }
 
-// func NewWriter(conf config.KafkaConfig) *kafka.Writer {
-// 	w := kafka.NewWriter(kafka.WriterConfig{
-// 		Brokers: []string{"localhost:9092"},
-// 		Topic:   conf.Topic,
-// 	})
-// 	return w
-// }
+// GroupHealthCheck reports an error when the consumer group has no active members
+// or lags more than maxLag messages behind the topic end (maxLag <= 0 disables the lag check)
+func GroupHealthCheck(conf config.KafkaConfig, maxLag int64) func(ctx context.Context) error {
+	client := &kafka.Client{Addr: kafka.TCP(conf.Broker)}
+	return func(ctx context.Context) error {
+		resp, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{conf.GroupID}})
+		if err != nil {
+			return fmt.Errorf("cannot describe consumer group: %w", err)
+		}
+		if len(resp.Groups) == 0 {
+			return fmt.Errorf("consumer group %s not found", conf.GroupID)
+		}
+		group := resp.Groups[0]
+		if group.Error != nil {
+			return fmt.Errorf("consumer group %s: %w", conf.GroupID, group.Error)
+		}
+		if group.GroupState != "Stable" || len(group.Members) == 0 {
+			return fmt.Errorf("consumer group %s is %s with %d members", conf.GroupID, group.GroupState, len(group.Members))
+		}
+
+		if maxLag <= 0 {
+			return nil
+		}
+		lag, err := GroupLag(ctx, client, conf)
+		if err != nil {
+			return err
+		}
+		if lag > maxLag {
+			return fmt.Errorf("consumer group lag %d exceeds %d", lag, maxLag)
+		}
+		return nil
+	}
+}
+
+// GroupLag sums the difference between the topic end offsets and the offsets committed by the group
+func GroupLag(ctx context.Context, client *kafka.Client, conf config.KafkaConfig) (int64, error) {
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{conf.Topic}})
+	if err != nil {
+		return 0, fmt.Errorf("cannot fetch topic metadata: %w", err)
+	}
+	if len(meta.Topics) == 0 || meta.Topics[0].Error != nil {
+		return 0, fmt.Errorf("topic %s is not available", conf.Topic)
+	}
+
+	partitions := make([]int, 0, len(meta.Topics[0].Partitions))
+	lastOffsets := make([]kafka.OffsetRequest, 0, len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		partitions = append(partitions, p.ID)
+		lastOffsets = append(lastOffsets, kafka.LastOffsetOf(p.ID))
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: conf.GroupID, Topics: map[string][]int{conf.Topic: partitions}})
+	if err != nil {
+		return 0, fmt.Errorf("cannot fetch committed offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return 0, fmt.Errorf("cannot fetch committed offsets: %w", committed.Error)
+	}
+
+	ends, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{conf.Topic: lastOffsets}})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list topic offsets: %w", err)
+	}
+
+	endByPartition := make(map[int]int64, len(partitions))
+	for _, p := range ends.Topics[conf.Topic] {
+		endByPartition[p.Partition] = p.LastOffset
+	}
+
+	var lag int64
+	for _, p := range committed.Topics[conf.Topic] {
+		//nothing committed yet, the whole partition is pending
+		offset := max(p.CommittedOffset, 0)
+		lag += max(endByPartition[p.Partition]-offset, 0)
+	}
+	return lag, nil
+}
+
+// NewDLQWriter returns a writer to the dead letter topic, or nil when it is not configured
+func NewDLQWriter(conf config.KafkaConfig) *kafka.Writer {
+	if conf.DLQTopic == "" {
+		return nil
+	}
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(conf.Broker),
+		Topic:                  conf.DLQTopic,
+		AllowAutoTopicCreation: true,
+		RequiredAcks:           kafka.RequireAll,
+	}
+}
+
+// NewWriter returns a writer to the orders topic, batches are flushed every batchTimeout
+func NewWriter(conf config.KafkaConfig, batchTimeout time.Duration) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(conf.Broker),
+		Topic:                  conf.Topic,
+		Balancer:               &kafka.Hash{},
+		AllowAutoTopicCreation: true,
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           batchTimeout,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"order_service/internal/config"
+	"order_service/internal/tracing"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -29,7 +30,14 @@ func New(ctx context.Context, config config.PostgresConfig) (*pgxpool.Pool, erro
 		config.Database,
 	)
 
-	conn, err := pgxpool.New(ctx, connstring)
+	poolConfig, err := pgxpool.ParseConfig(connstring)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse postgres config:%v", err)
+	}
+	//every statement gets its own span
+	poolConfig.ConnConfig.Tracer = tracing.PgxTracer{}
+
+	conn, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return conn, fmt.Errorf("unable to connect to the postgres:%v", err)
 	}
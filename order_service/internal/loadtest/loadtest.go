@@ -0,0 +1,350 @@
+package loadtest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Distribution is how the next id is picked from the pool
+type Distribution string
+
+const (
+	// Uniform gives every id the same chance
+	Uniform Distribution = "uniform"
+	// Zipfian makes a few ids hot and most of them cold, like real lookups
+	Zipfian Distribution = "zipfian"
+)
+
+func ParseDistribution(s string) (Distribution, error) {
+	switch d := Distribution(s); d {
+	case Uniform, Zipfian:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unknown id distribution %q, want uniform or zipfian", s)
+	}
+}
+
+type Options struct {
+	// BaseURL is where the service listens, e.g. http://localhost:8081
+	BaseURL string
+	// APIKey is sent as X-API-Key when not empty
+	APIKey      string
+	Concurrency int
+	Duration    time.Duration
+	// Timeout bounds a single request
+	Timeout time.Duration
+	// IDs is the pool of existing order ids
+	IDs          []string
+	Distribution Distribution
+	// ZipfS is the zipfian exponent, it must be > 1, higher is more skewed
+	ZipfS float64
+	// Unknown is the share of requests in [0, 1] asking for ids that do not exist
+	Unknown float64
+	Seed    int64
+}
+
+// Report is what Run measured, the JSON form is meant for comparing runs
+type Report struct {
+	Requests   int            `json:"requests"`
+	Duration   time.Duration  `json:"duration_ns"`
+	Throughput float64        `json:"throughput_rps"`
+	Latency    Latency        `json:"latency"`
+	Status     map[string]int `json:"status"`
+	Errors     map[string]int `json:"errors"`
+	// Unknown counts the requests for ids that do not exist, a 404 is expected for them
+	Unknown int `json:"unknown"`
+	// Cache is the service's cache lookups during the run, nil when /metrics could not be read
+	Cache *CacheStats `json:"cache,omitempty"`
+	// Warnings are problems that left parts of the report out
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// CacheStats is the difference of the service's cache lookup counters before and after
+// a run. It covers every cache lookup of the scraped replica, other traffic included.
+type CacheStats struct {
+	Hits     int     `json:"hits"`
+	Misses   int     `json:"misses"`
+	Errors   int     `json:"errors"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// Latency percentiles are in milliseconds
+type Latency struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+	P999 float64 `json:"p999_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+// ErrorCount sums the error breakdown
+func (r Report) ErrorCount() int {
+	n := 0
+	for _, c := range r.Errors {
+		n += c
+	}
+	return n
+}
+
+// Run calls GET /order/{id} from Concurrency workers until Duration passes or ctx is cancelled.
+// Every worker sends its next request as soon as the previous one is answered. The cache
+// hit ratio comes from the service's /metrics, scraped before and after the run.
+func Run(ctx context.Context, opts Options, client *http.Client) (Report, error) {
+	if opts.Concurrency < 1 {
+		return Report{}, errors.New("loadtest: concurrency must be at least 1")
+	}
+	if opts.Unknown < 0 || opts.Unknown > 1 {
+		return Report{}, fmt.Errorf("loadtest: unknown share must be in [0, 1], got %v", opts.Unknown)
+	}
+	if len(opts.IDs) == 0 && opts.Unknown < 1 {
+		return Report{}, errors.New("loadtest: no order ids to ask for, only -unknown 1 works without them")
+	}
+	if opts.Distribution == Zipfian && opts.ZipfS <= 1 {
+		return Report{}, fmt.Errorf("loadtest: zipf exponent must be > 1, got %v", opts.ZipfS)
+	}
+	if _, err := url.Parse(opts.BaseURL); err != nil {
+		return Report{}, fmt.Errorf("loadtest: base url: %w", err)
+	}
+
+	var warnings []string
+	cacheBefore, err := scrapeCache(ctx, opts, client)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("cache hit ratio left out: %v", err))
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	results := make([]workerResult, opts.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = worker(runCtx, opts, client, rand.New(rand.NewSource(opts.Seed+int64(i))))
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := Report{Duration: elapsed, Status: map[string]int{}, Errors: map[string]int{}}
+	var latencies []time.Duration
+	for _, res := range results {
+		latencies = append(latencies, res.latencies...)
+		report.Unknown += res.unknown
+		for k, v := range res.status {
+			report.Status[k] += v
+		}
+		for k, v := range res.errors {
+			report.Errors[k] += v
+		}
+	}
+	report.Requests = len(latencies)
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	report.Latency = percentiles(latencies)
+
+	if cacheBefore != nil {
+		cacheAfter, err := scrapeCache(ctx, opts, client)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("cache hit ratio left out: %v", err))
+		} else {
+			report.Cache = cacheAfter.since(cacheBefore)
+		}
+	}
+	report.Warnings = warnings
+	return report, nil
+}
+
+// cacheMetric is the service's cache lookup counter, labelled by result
+const cacheMetric = "order_service_cache_lookups_total"
+
+// scrapeCache reads the cache lookup counters from GET /metrics
+func scrapeCache(ctx context.Context, opts Options, client *http.Client) (*CacheStats, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, opts.BaseURL+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scrape metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape metrics: GET /metrics returned %s", resp.Status)
+	}
+
+	stats := &CacheStats{}
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		//order_service_cache_lookups_total{result="hit"} 42
+		series, value, ok := strings.Cut(sc.Text(), " ")
+		if !ok || !strings.HasPrefix(series, cacheMetric+"{") {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("scrape metrics: %s: %w", series, err)
+		}
+		switch {
+		case strings.Contains(series, `result="hit"`):
+			stats.Hits = int(n)
+		case strings.Contains(series, `result="miss"`):
+			stats.Misses = int(n)
+		case strings.Contains(series, `result="error"`):
+			stats.Errors = int(n)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("scrape metrics: %w", err)
+	}
+	return stats, nil
+}
+
+// since is the lookups between before and s
+func (s *CacheStats) since(before *CacheStats) *CacheStats {
+	d := &CacheStats{Hits: s.Hits - before.Hits, Misses: s.Misses - before.Misses, Errors: s.Errors - before.Errors}
+	if total := d.Hits + d.Misses + d.Errors; total > 0 {
+		d.HitRatio = float64(d.Hits) / float64(total)
+	}
+	return d
+}
+
+type workerResult struct {
+	latencies []time.Duration
+	status    map[string]int
+	errors    map[string]int
+	unknown   int
+}
+
+func worker(ctx context.Context, opts Options, client *http.Client, rnd *rand.Rand) workerResult {
+	res := workerResult{status: map[string]int{}, errors: map[string]int{}}
+	var zipf *rand.Zipf
+	if opts.Distribution == Zipfian && len(opts.IDs) > 0 {
+		zipf = rand.NewZipf(rnd, opts.ZipfS, 1, uint64(len(opts.IDs)-1))
+	}
+
+	for ctx.Err() == nil {
+		var id string
+		unknown := len(opts.IDs) == 0 || rnd.Float64() < opts.Unknown
+		switch {
+		case unknown:
+			id = fmt.Sprintf("loadtest-unknown-%016x", rnd.Uint64())
+		case zipf != nil:
+			id = opts.IDs[zipf.Uint64()]
+		default:
+			id = opts.IDs[rnd.Intn(len(opts.IDs))]
+		}
+
+		status, latency, err := get(ctx, opts, client, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				//the run ended while the request was in flight, it is not a failure
+				break
+			}
+			res.errors[errorKind(err)]++
+			res.latencies = append(res.latencies, latency)
+			continue
+		}
+		res.latencies = append(res.latencies, latency)
+		res.status[strconv.Itoa(status)]++
+		if unknown {
+			res.unknown++
+		}
+		switch {
+		case status == http.StatusOK:
+		case status == http.StatusNotFound && unknown:
+		case status == http.StatusNotFound:
+			res.errors["unexpected_404"]++
+		default:
+			res.errors["status_"+strconv.Itoa(status)]++
+		}
+	}
+	return res
+}
+
+func get(ctx context.Context, opts Options, client *http.Client, id string) (int, time.Duration, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, opts.BaseURL+"/order/"+url.PathEscape(id), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if opts.APIKey != "" {
+		req.Header.Set("X-API-Key", opts.APIKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, time.Since(start), err
+	}
+	//the body is read so the latency covers the whole response and the connection is reused
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, time.Since(start), err
+}
+
+func errorKind(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "truncated"
+	default:
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return "connect"
+		}
+		return "transport"
+	}
+}
+
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	slices.Sort(latencies)
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	at := func(p float64) float64 {
+		i := int(p*float64(len(latencies))+0.5) - 1
+		return ms(latencies[min(max(i, 0), len(latencies)-1)])
+	}
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	return Latency{
+		Mean: ms(sum / time.Duration(len(latencies))),
+		P50:  at(0.50),
+		P90:  at(0.90),
+		P95:  at(0.95),
+		P99:  at(0.99),
+		P999: at(0.999),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+}
@@ -0,0 +1,109 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeService answers every known id, the first lookup of an id is a cache miss
+func fakeService(known ...string) http.Handler {
+	var (
+		mu           sync.Mutex
+		hits, misses int
+		seen         = map[string]bool{}
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /order/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := r.PathValue("id")
+		if !strings.HasPrefix(id, "known") {
+			misses++
+			http.NotFound(w, r)
+			return
+		}
+		if seen[id] {
+			hits++
+		} else {
+			misses++
+			seen[id] = true
+		}
+		fmt.Fprintf(w, `{"order_uid": %q}`, id)
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "# TYPE %s counter\n", cacheMetric)
+		fmt.Fprintf(w, "%s{result=\"error\"} 0\n%s{result=\"hit\"} %d\n%s{result=\"miss\"} %d\n", cacheMetric, cacheMetric, hits, cacheMetric, misses)
+		fmt.Fprintln(w, `order_service_http_requests_total{code="200"} 1e+06`)
+	})
+	return mux
+}
+
+func TestRunReportsCacheHits(t *testing.T) {
+	srv := httptest.NewServer(fakeService())
+	defer srv.Close()
+	ids := []string{"known1", "known2", "known3"}
+	const workers = 2
+
+	report, err := Run(context.Background(), Options{
+		BaseURL: srv.URL, Concurrency: workers, Duration: 200 * time.Millisecond, Timeout: time.Second,
+		IDs: ids, Distribution: Uniform, Unknown: 0.2, Seed: 1,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Warnings) != 0 {
+		t.Fatalf("warnings %q", report.Warnings)
+	}
+	c := report.Cache
+	if c == nil {
+		t.Fatal("no cache stats")
+	}
+	//a request per worker may be cut off by the end of the run, the service counted it but the report didn't
+	const cutOff = workers
+	if n := c.Hits + c.Misses; n < report.Requests || n > report.Requests+cutOff {
+		t.Fatalf("%d hits and %d misses for %d requests", c.Hits, c.Misses, report.Requests)
+	}
+	//each known id misses once, unknown ids always miss
+	if want := len(ids) + report.Unknown; c.Misses < want || c.Misses > want+cutOff {
+		t.Fatalf("%d misses, want %d", c.Misses, want)
+	}
+	if c.Hits == 0 {
+		t.Fatal("no hits")
+	}
+	if want := float64(c.Hits) / float64(c.Hits+c.Misses); c.HitRatio != want {
+		t.Fatalf("hit ratio %v, want %v", c.HitRatio, want)
+	}
+}
+
+func TestRunWithoutMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	report, err := Run(context.Background(), Options{
+		BaseURL: srv.URL, Concurrency: 1, Duration: 50 * time.Millisecond, Timeout: time.Second,
+		IDs: []string{"known1"}, Distribution: Uniform,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Cache != nil || len(report.Warnings) != 1 {
+		t.Fatalf("cache %+v, warnings %q: want no stats and a warning", report.Cache, report.Warnings)
+	}
+	if report.Requests == 0 || report.ErrorCount() != 0 {
+		t.Fatalf("%d requests with %d errors", report.Requests, report.ErrorCount())
+	}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+// PoolCollector exports pgxpool.Stat() on every scrape
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired, idle, total, max *prometheus.Desc
+	acquires, emptyAcquires    *prometheus.Desc
+	canceledAcquires           *prometheus.Desc
+	acquireDuration            *prometheus.Desc
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:             pool,
+		acquired:         desc("acquired_conns", "Connections currently acquired from the pool."),
+		idle:             desc("idle_conns", "Idle connections in the pool."),
+		total:            desc("total_conns", "Total connections in the pool."),
+		max:              desc("max_conns", "Maximum size of the pool."),
+		acquires:         desc("acquires_total", "Successful connection acquires."),
+		emptyAcquires:    desc("empty_acquires_total", "Acquires that had to wait for a connection because the pool was empty."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires cancelled by their context."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Total time spent waiting for connections."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
+
+// ReaderCollector exports kafka.Reader.Stats() on every scrape.
+// Stats() resets its counters on each call, so the collector keeps running totals
+// and must be the only caller of Stats() for the reader.
+type ReaderCollector struct {
+	reader *kafka.Reader
+
+	mu                                       sync.Mutex
+	messages, bytes, errors, fetches, rebals int64
+
+	lagDesc, messagesDesc, bytesDesc, errorsDesc, fetchesDesc, rebalancesDesc *prometheus.Desc
+}
+
+func NewReaderCollector(reader *kafka.Reader) *ReaderCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "kafka_reader", name), help, []string{"topic"}, nil)
+	}
+	return &ReaderCollector{
+		reader:         reader,
+		lagDesc:        desc("lag", "Messages between the last fetched offset and the partition end."),
+		messagesDesc:   desc("messages_total", "Messages fetched by the reader."),
+		bytesDesc:      desc("bytes_total", "Bytes fetched by the reader."),
+		errorsDesc:     desc("errors_total", "Reader errors."),
+		fetchesDesc:    desc("fetches_total", "Fetch requests sent by the reader."),
+		rebalancesDesc: desc("rebalances_total", "Consumer group rebalances."),
+	}
+}
+
+func (c *ReaderCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *ReaderCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.reader.Stats()
+	c.messages += s.Messages
+	c.bytes += s.Bytes
+	c.errors += s.Errors
+	c.fetches += s.Fetches
+	c.rebals += s.Rebalances
+
+	ch <- prometheus.MustNewConstMetric(c.lagDesc, prometheus.GaugeValue, float64(s.Lag), s.Topic)
+	ch <- prometheus.MustNewConstMetric(c.messagesDesc, prometheus.CounterValue, float64(c.messages), s.Topic)
+	ch <- prometheus.MustNewConstMetric(c.bytesDesc, prometheus.CounterValue, float64(c.bytes), s.Topic)
+	ch <- prometheus.MustNewConstMetric(c.errorsDesc, prometheus.CounterValue, float64(c.errors), s.Topic)
+	ch <- prometheus.MustNewConstMetric(c.fetchesDesc, prometheus.CounterValue, float64(c.fetches), s.Topic)
+	ch <- prometheus.MustNewConstMetric(c.rebalancesDesc, prometheus.CounterValue, float64(c.rebals), s.Topic)
+}
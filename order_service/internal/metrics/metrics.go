@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "order_service"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by chi route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by chi route pattern, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	httpRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "HTTP requests rejected with 429 by chi route pattern.",
+	}, []string{"route"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Order cache lookups by result: hit, miss or error.",
+	}, []string{"result"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "query_duration_seconds",
+		Help:      "Postgres query latency by query name and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query", "outcome"})
+
+	kafkaMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "messages_total",
+		Help:      "Kafka messages by result: processed, decode_failed or process_failed.",
+	}, []string{"result"})
+
+	decodeUnknownFields = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "decode",
+		Name:      "unknown_fields_total",
+		Help:      "Unknown JSON fields accepted in lenient mode by source and field path, paths past the first 100 are counted as other.",
+	}, []string{"source", "field"})
+
+	kafkaDLQ = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "dlq_messages_total",
+		Help:      "Messages sent to the dead letter topic by outcome: published or failed.",
+	}, []string{"outcome"})
+)
+
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+
+	KafkaProcessed     = "processed"
+	KafkaDecodeFailed  = "decode_failed"
+	KafkaProcessFailed = "process_failed"
+)
+
+func RateLimited(route string) {
+	httpRateLimited.WithLabelValues(route).Inc()
+}
+
+func CacheLookup(result string) {
+	cacheLookups.WithLabelValues(result).Inc()
+}
+
+// StartQuery starts timing a named query, the returned func records it once the query is done
+func StartQuery(query string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		dbQueryDuration.WithLabelValues(query, outcome).Observe(time.Since(start).Seconds())
+	}
+}
+
+func KafkaMessage(result string) {
+	kafkaMessages.WithLabelValues(result).Inc()
+}
+
+// maxUnknownFieldPaths bounds the field label, the paths come from untrusted payloads
+const maxUnknownFieldPaths = 100
+
+var (
+	unknownFieldsMu   sync.Mutex
+	unknownFieldPaths = map[string]bool{}
+)
+
+// UnknownField counts a field the decoder ignored, source is http, kafka or import
+func UnknownField(source, path string) {
+	if len(path) > 64 {
+		//label values must stay valid UTF-8, the cut may split a rune
+		path = strings.ToValidUTF8(path[:64], "")
+	}
+	unknownFieldsMu.Lock()
+	if !unknownFieldPaths[path] {
+		if len(unknownFieldPaths) < maxUnknownFieldPaths {
+			unknownFieldPaths[path] = true
+		} else {
+			path = "other"
+		}
+	}
+	unknownFieldsMu.Unlock()
+	decodeUnknownFields.WithLabelValues(source, path).Inc()
+}
+
+func KafkaDLQ(err error) {
+	outcome := "published"
+	if err != nil {
+		outcome = "failed"
+	}
+	kafkaDLQ.WithLabelValues(outcome).Inc()
+}
+
+// Handler exposes every registered collector in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records request counts and latency, labelled by the chi route pattern
+// instead of the raw path so order ids don't explode the label cardinality
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		//the pattern is only known after routing, so it is read once the handler returns
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := []string{route, r.Method, strconv.Itoa(status)}
+		httpRequests.WithLabelValues(labels...).Inc()
+		httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleWriter Role = "writer"
+	RoleAdmin  Role = "admin"
+)
+
+// rank orders the roles, every role can do what the roles below it can
+var rank = map[Role]int{RoleReader: 1, RoleWriter: 2, RoleAdmin: 3}
+
+func (r Role) Valid() bool {
+	_, ok := rank[r]
+	return ok
+}
+
+// Allows reports whether r is at least required
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && rank[r] >= rank[required]
+}
+
+// APIKey is a stored key, only the hash of the secret is ever kept
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
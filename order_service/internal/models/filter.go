@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OrderFilter selects orders for listing and exports, zero values are not applied.
+// Results are ordered by order_uid, AfterUID is the keyset cursor for the next page.
+type OrderFilter struct {
+	CustomerID      string
+	DeliveryService string
+	MinAmount       int
+	CreatedFrom     time.Time
+	CreatedTo       time.Time
+	AfterUID        string
+	Limit           int
+}
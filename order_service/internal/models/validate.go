@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"order_service/internal/errdef"
+)
+
+// Validate checks the invariants the storage relies on (required fields, non-negative
+// amounts) and returns an *errdef.ValidationError listing every violation
+func (o Order) Validate() error {
+	var v errdef.ValidationError
+
+	required := func(field, value string) {
+		if value == "" {
+			v.Add(field, "is required")
+		}
+	}
+	nonNegative := func(field string, value int) {
+		if value < 0 {
+			v.Add(field, "must not be negative")
+		}
+	}
+
+	required("order_uid", o.OrderUID)
+	required("track_number", o.TrackNumber)
+	required("entry", o.Entry)
+	required("locale", o.Locale)
+	required("customer_id", o.CustomerID)
+	required("delivery_service", o.DeliveryService)
+	required("shardkey", o.ShardKey)
+	required("oof_shard", o.OofShard)
+	nonNegative("sm_id", o.SmID)
+	if o.DateCreated.IsZero() {
+		v.Add("date_created", "is required")
+	}
+
+	required("delivery.name", o.Delivery.Name)
+	required("delivery.phone", o.Delivery.Phone)
+	required("delivery.city", o.Delivery.City)
+	required("delivery.address", o.Delivery.Address)
+
+	required("payment.transaction", o.Payment.Transaction)
+	required("payment.currency", o.Payment.Currency)
+	required("payment.provider", o.Payment.Provider)
+	nonNegative("payment.amount", o.Payment.Amount)
+	nonNegative("payment.delivery_cost", o.Payment.DeliveryCost)
+	nonNegative("payment.goods_total", o.Payment.GoodsTotal)
+	nonNegative("payment.custom_fee", o.Payment.CustomFee)
+
+	if len(o.Items) == 0 {
+		v.Add("items", "at least one item is required")
+	}
+	for i, it := range o.Items {
+		prefix := fmt.Sprintf("items[%d].", i)
+		required(prefix+"track_number", it.TrackNumber)
+		required(prefix+"name", it.Name)
+		nonNegative(prefix+"price", it.Price)
+		nonNegative(prefix+"sale", it.Sale)
+		nonNegative(prefix+"total_price", it.TotalPrice)
+		nonNegative(prefix+"nm_id", it.NmID)
+		nonNegative(prefix+"status", it.Status)
+	}
+
+	return v.Err()
+}
@@ -0,0 +1,145 @@
+// Package orderschema versions the order message format. A message names its version in
+// the schema_version field, messages without it are version 1. Older versions are upcast
+// one step at a time to the Current one, which is what models.Order decodes; versions newer
+// than Current are rejected, so the recievers dead-letter them instead of guessing.
+//
+// Changing the format means bumping Current, adding schemas/order.vN.json and registering
+// an Upcaster from the previous version in Default.
+package orderschema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"order_service/internal/decoding"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"strings"
+)
+
+// Field is the JSON field carrying the version
+const Field = "schema_version"
+
+// Current is the version models.Order corresponds to
+const Current = 1
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// Schema returns the JSON Schema of version
+func Schema(version int) ([]byte, error) {
+	return schemas.ReadFile(fmt.Sprintf("schemas/order.v%d.json", version))
+}
+
+// Stamp adds schema_version to the encoded message object b, anything else is returned as is
+func Stamp(b []byte, version int) []byte {
+	rest, ok := bytes.CutPrefix(bytes.TrimSpace(b), []byte("{"))
+	if !ok {
+		return b
+	}
+	rest = bytes.TrimSpace(rest)
+	sep := ","
+	if len(rest) > 0 && rest[0] == '}' {
+		sep = ""
+	}
+	return fmt.Appendf(nil, `{"%s":%d%s%s`, Field, version, sep, rest)
+}
+
+// Upcaster rewrites a message object of one version into the next version in place,
+// the schema_version field is already removed
+type Upcaster func(obj map[string]json.RawMessage) error
+
+// Registry decodes messages of every version up to current into models.Order
+type Registry struct {
+	current   int
+	upcasters map[int]Upcaster
+	decoder   decoding.Decoder
+}
+
+// New returns a registry reading versions 1 to current with d, an Upcaster has to be
+// registered for every version below current
+func New(current int, d decoding.Decoder) *Registry {
+	return &Registry{current: current, upcasters: map[int]Upcaster{}, decoder: d}
+}
+
+// Default is the registry of the versions this service reads
+func Default(d decoding.Decoder) *Registry {
+	return New(Current, d)
+}
+
+// Register adds the upcaster from version from to from+1
+func (r *Registry) Register(from int, up Upcaster) error {
+	if from < 1 || from >= r.current {
+		return fmt.Errorf("upcaster from version %d: versions 1 to %d can be upcast", from, r.current-1)
+	}
+	if _, ok := r.upcasters[from]; ok {
+		return fmt.Errorf("upcaster from version %d is already registered", from)
+	}
+	r.upcasters[from] = up
+	return nil
+}
+
+// Decode reads the version of b, upcasts it to the current one and decodes it. Errors wrap
+// errdef.ErrInvalidInput or errdef.ErrTooLarge like the decoder's.
+func (r *Registry) Decode(b []byte) (models.Order, error) {
+	var o models.Order
+	if err := r.decoder.CheckSize(len(b)); err != nil {
+		return o, err
+	}
+	version, stamped, err := peekVersion(b)
+	if err != nil {
+		return o, err
+	}
+	if version > r.current {
+		return o, fmt.Errorf("%w: %s %d is newer than %d, the latest this service reads", errdef.ErrInvalidInput, Field, version, r.current)
+	}
+	//the common case: a current message nothing has to be rewritten for
+	if !stamped && version == r.current {
+		return o, r.decoder.Decode(b, &o)
+	}
+
+	var obj map[string]json.RawMessage
+	if err := r.decoder.Decode(b, &obj); err != nil {
+		return o, err
+	}
+	if obj == nil {
+		return o, fmt.Errorf("%w: the message is not an object", errdef.ErrInvalidInput)
+	}
+	for key := range obj {
+		//encoding/json matched the field case-insensitively, so does the removal
+		if strings.EqualFold(key, Field) {
+			delete(obj, key)
+		}
+	}
+	for v := version; v < r.current; v++ {
+		up, ok := r.upcasters[v]
+		if !ok {
+			return o, fmt.Errorf("no upcaster from %s %d", Field, v)
+		}
+		if err := up(obj); err != nil {
+			return o, fmt.Errorf("%w: upcast from %s %d: %w", errdef.ErrInvalidInput, Field, v, err)
+		}
+	}
+
+	upcast, err := json.Marshal(obj)
+	if err != nil {
+		return o, fmt.Errorf("encode upcast message: %w", err)
+	}
+	return o, r.decoder.Decode(upcast, &o)
+}
+
+// peekVersion reads schema_version without decoding the rest, stamped is false when it is
+// missing. Malformed messages are reported as version 1, decoding them reports the problem.
+func peekVersion(b []byte) (version int, stamped bool, err error) {
+	var probe struct {
+		Version json.RawMessage `json:"schema_version"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(b)).Decode(&probe); err != nil || probe.Version == nil {
+		return 1, false, nil
+	}
+	if err := json.Unmarshal(probe.Version, &version); err != nil || version < 1 {
+		return 0, true, fmt.Errorf("%w: %s must be a positive integer", errdef.ErrInvalidInput, Field)
+	}
+	return version, true, nil
+}
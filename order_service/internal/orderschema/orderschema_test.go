@@ -0,0 +1,288 @@
+package orderschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order_service/internal/decoding"
+	"order_service/internal/errdef"
+	"order_service/internal/generator"
+	"order_service/internal/models"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func loadSchema(t *testing.T, version int) *openapi3.Schema {
+	t.Helper()
+	b, err := Schema(version)
+	if err != nil {
+		t.Fatalf("schema of version %d: %v", version, err)
+	}
+	var s openapi3.Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatalf("schema of version %d: %v", version, err)
+	}
+	return &s
+}
+
+// schemaErr is the first violation only, the full error repeats the whole schema
+func schemaErr(err error) string {
+	var serr *openapi3.SchemaError
+	if errors.As(err, &serr) {
+		return fmt.Sprintf("%s: %s", serr.JSONPointer(), serr.Reason)
+	}
+	return err.Error()
+}
+
+func strictDecoder(t *testing.T) decoding.Decoder {
+	t.Helper()
+	d, err := decoding.New(1<<20, decoding.Reject, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestEveryVersionHasASchema(t *testing.T) {
+	for v := 1; v <= Current; v++ {
+		loadSchema(t, v)
+	}
+	if _, err := Schema(Current + 1); err == nil {
+		t.Fatalf("there is a schema for version %d, bump Current", Current+1)
+	}
+}
+
+// TestCurrentSchemaMatchesModel fails when a models.Order json tag changes without a new version
+func TestCurrentSchemaMatchesModel(t *testing.T) {
+	var fromSchema, fromModel []string
+	var walkSchema func(s *openapi3.Schema, prefix string)
+	walkSchema = func(s *openapi3.Schema, prefix string) {
+		if s.Items != nil {
+			walkSchema(s.Items.Value, prefix)
+		}
+		for name, p := range s.Properties {
+			if prefix == "" && name == Field {
+				continue
+			}
+			fromSchema = append(fromSchema, prefix+name)
+			walkSchema(p.Value, prefix+name+".")
+		}
+	}
+	walkSchema(loadSchema(t, Current), "")
+
+	var walkModel func(typ reflect.Type, prefix string)
+	walkModel = func(typ reflect.Type, prefix string) {
+		if typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || typ == reflect.TypeFor[time.Time]() {
+			return
+		}
+		for i := range typ.NumField() {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			fromModel = append(fromModel, prefix+name)
+			walkModel(typ.Field(i).Type, prefix+name+".")
+		}
+	}
+	walkModel(reflect.TypeFor[models.Order](), "")
+
+	slices.Sort(fromSchema)
+	slices.Sort(fromModel)
+	if !slices.Equal(fromSchema, fromModel) {
+		t.Fatalf("schemas/order.v%d.json and models.Order differ, a changed format needs a new version:\nschema %q\nmodel  %q", Current, fromSchema, fromModel)
+	}
+}
+
+func TestCurrentSchemaAgreesWithValidate(t *testing.T) {
+	schema := loadSchema(t, Current)
+	gen, err := generator.New(generator.Options{Seed: 1, MinItems: 1, MaxItems: 3, CorruptRate: 0.5, Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := Default(strictDecoder(t))
+
+	for range 100 {
+		b, _, corruption, err := gen.Record()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc any
+		docErr := json.Unmarshal(b, &doc)
+		if docErr == nil {
+			docErr = schema.VisitJSON(doc)
+		}
+		o, err := registry.Decode(b)
+		if err == nil {
+			err = o.Validate()
+		}
+
+		if (docErr == nil) != (err == nil) {
+			var reason string
+			if docErr != nil {
+				reason = schemaErr(docErr)
+			}
+			t.Fatalf("%q record: schema error %q, service error %v\n%s", corruption, reason, err, b)
+		}
+		if corruption == "" && err != nil {
+			t.Fatalf("valid record rejected: %v", err)
+		}
+	}
+}
+
+func TestStampedCurrentVersionDecodes(t *testing.T) {
+	gen, err := generator.New(generator.Options{Seed: 1, MinItems: 1, MaxItems: 2, Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := gen.Order()
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamped := Stamp(b, Current)
+
+	var doc any
+	if err := json.Unmarshal(stamped, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadSchema(t, Current).VisitJSON(doc); err != nil {
+		t.Fatalf("stamped message does not match its schema: %s", schemaErr(err))
+	}
+
+	//the strict decoder would reject schema_version as an unknown field if it was left in
+	got, err := Default(strictDecoder(t)).Decode(stamped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Fatalf("date_created: got %s, want %s", got.DateCreated, want.DateCreated)
+	}
+	got.DateCreated, want.DateCreated = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestStamp(t *testing.T) {
+	for in, want := range map[string]string{
+		`{"order_uid": "x"}`: `{"schema_version":2,"order_uid": "x"}`,
+		` { } `:              `{"schema_version":2}`,
+		`{"order_uid":`:      `{"schema_version":2,"order_uid":`,
+		`[]`:                 `[]`,
+	} {
+		if got := string(Stamp([]byte(in), 2)); got != want {
+			t.Errorf("Stamp(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestUnsupportedVersions(t *testing.T) {
+	registry := Default(strictDecoder(t))
+	for _, version := range []string{fmt.Sprint(Current + 1), "0", "-1", "1.5", `"1"`, "null"} {
+		_, err := registry.Decode([]byte(`{"schema_version": ` + version + `, "order_uid": "x"}`))
+		if !errors.Is(err, errdef.ErrInvalidInput) {
+			t.Errorf("schema_version %s: got %v, want invalid input", version, err)
+		}
+	}
+}
+
+// history is a made up format history for the upcasting machinery: version 1 called the
+// customer "customer", version 2 still had the amount at the top level
+func history(t *testing.T) *Registry {
+	t.Helper()
+	r := New(3, strictDecoder(t))
+	rename := func(obj map[string]json.RawMessage) error {
+		if v, ok := obj["customer"]; ok {
+			obj["customer_id"] = v
+			delete(obj, "customer")
+		}
+		return nil
+	}
+	moveAmount := func(obj map[string]json.RawMessage) error {
+		amount, ok := obj["amount"]
+		if !ok {
+			return nil
+		}
+		delete(obj, "amount")
+		var payment map[string]json.RawMessage
+		if p, ok := obj["payment"]; ok {
+			if err := json.Unmarshal(p, &payment); err != nil {
+				return fmt.Errorf("payment: %w", err)
+			}
+		}
+		if payment == nil {
+			payment = map[string]json.RawMessage{}
+		}
+		payment["amount"] = amount
+		p, err := json.Marshal(payment)
+		if err != nil {
+			return err
+		}
+		obj["payment"] = p
+		return nil
+	}
+	if err := r.Register(1, rename); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(2, moveAmount); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestUpcasting(t *testing.T) {
+	r := history(t)
+	tests := []struct {
+		name string
+		msg  string
+	}{
+		{"unstamped is version 1", `{"customer": "c1", "amount": 100, "payment": {"currency": "RUB"}}`},
+		{"version 1", `{"schema_version": 1, "customer": "c1", "amount": 100, "payment": {"currency": "RUB"}}`},
+		{"version 2", `{"schema_version": 2, "customer_id": "c1", "amount": 100, "payment": {"currency": "RUB"}}`},
+		{"version 3", `{"schema_version": 3, "customer_id": "c1", "payment": {"currency": "RUB", "amount": 100}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := r.Decode([]byte(tt.msg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.CustomerID != "c1" || o.Payment.Amount != 100 || o.Payment.Currency != "RUB" {
+				t.Fatalf("got %+v", o)
+			}
+		})
+	}
+
+	//a field the upcasters don't know is still subject to the decoder's policy
+	if _, err := r.Decode([]byte(`{"schema_version": 1, "colour": "red"}`)); !errors.Is(err, errdef.ErrInvalidInput) {
+		t.Fatalf("unknown field after upcasting: got %v, want invalid input", err)
+	}
+	if _, err := r.Decode([]byte(`{"schema_version": 2, "amount": 1, "payment": "cash"}`)); !errors.Is(err, errdef.ErrInvalidInput) {
+		t.Fatalf("failed upcast: got %v, want invalid input", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := New(3, strictDecoder(t))
+	noop := func(map[string]json.RawMessage) error { return nil }
+	for _, from := range []int{0, 3, 4} {
+		if err := r.Register(from, noop); err == nil {
+			t.Errorf("upcaster from version %d was accepted", from)
+		}
+	}
+	if err := r.Register(1, noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(1, noop); err == nil {
+		t.Error("duplicate upcaster was accepted")
+	}
+	//version 2 has no upcaster, messages of it can't be read
+	if _, err := r.Decode([]byte(`{"schema_version": 2}`)); err == nil {
+		t.Error("a message with a gap in the upcasters was decoded")
+	}
+}
@@ -0,0 +1,195 @@
+package pii
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"order_service/internal/auth"
+	"order_service/internal/feed"
+	"order_service/internal/models"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Strategy says how a field is masked
+type Strategy string
+
+const (
+	// Keep shows the value as is
+	Keep Strategy = "none"
+	// Full replaces the whole value
+	Full Strategy = "full"
+	// Partial keeps the first character: J***
+	Partial Strategy = "partial"
+	// Phone keeps the first two and last four characters: +7***1234
+	Phone Strategy = "phone"
+	// Email keeps the first character of the local part and the domain: j***@mail.ru
+	Email Strategy = "email"
+)
+
+const mask = "***"
+
+// Policy maps a field (delivery.phone, customer_id, ...) to its strategy
+type Policy map[string]Strategy
+
+// fields are the fields a policy can mask, with setters on a copy of the order
+var fields = map[string]func(o *models.Order) *string{
+	"delivery.name":    func(o *models.Order) *string { return &o.Delivery.Name },
+	"delivery.phone":   func(o *models.Order) *string { return &o.Delivery.Phone },
+	"delivery.zip":     func(o *models.Order) *string { return &o.Delivery.Zip },
+	"delivery.city":    func(o *models.Order) *string { return &o.Delivery.City },
+	"delivery.address": func(o *models.Order) *string { return &o.Delivery.Address },
+	"delivery.region":  func(o *models.Order) *string { return &o.Delivery.Region },
+	"delivery.email":   func(o *models.Order) *string { return &o.Delivery.Email },
+	"customer_id":      func(o *models.Order) *string { return &o.CustomerID },
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		"delivery.name":    Partial,
+		"delivery.phone":   Phone,
+		"delivery.zip":     Full,
+		"delivery.city":    Keep,
+		"delivery.address": Full,
+		"delivery.region":  Keep,
+		"delivery.email":   Email,
+		"customer_id":      Keep,
+	}
+}
+
+// ParsePolicy reads "field=strategy,field=strategy" on top of the default policy
+func ParsePolicy(s string) (Policy, error) {
+	p := DefaultPolicy()
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, strategy, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("pii policy: %q is not field=strategy", pair)
+		}
+		field, strategy = strings.TrimSpace(field), strings.TrimSpace(strategy)
+		if _, ok := fields[field]; !ok {
+			return nil, fmt.Errorf("pii policy: unknown field %q, known fields: %s", field, strings.Join(knownFields(), ", "))
+		}
+		switch st := Strategy(strategy); st {
+		case Keep, Full, Partial, Phone, Email:
+			p[field] = st
+		default:
+			return nil, fmt.Errorf("pii policy: unknown strategy %q for %s", strategy, field)
+		}
+	}
+	return p, nil
+}
+
+func knownFields() []string {
+	names := make([]string, 0, len(fields))
+	for f := range fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply masks s with strategy st
+func (st Strategy) Apply(s string) string {
+	if s == "" || st == Keep {
+		return s
+	}
+	switch st {
+	case Partial:
+		r, _ := utf8.DecodeRuneInString(s)
+		return string(r) + mask
+	case Phone:
+		if utf8.RuneCountInString(s) <= 6 {
+			return mask
+		}
+		runes := []rune(s)
+		return string(runes[:2]) + mask + string(runes[len(runes)-4:])
+	case Email:
+		local, domain, ok := strings.Cut(s, "@")
+		if !ok || local == "" {
+			return Partial.Apply(s)
+		}
+		return Partial.Apply(local) + "@" + domain
+	default:
+		return mask
+	}
+}
+
+// Order returns a masked copy of o
+func (p Policy) Order(o models.Order) models.Order {
+	for field, st := range p {
+		if v := fields[field](&o); *v != "" {
+			*v = st.Apply(*v)
+		}
+	}
+	return o
+}
+
+// Masker applies a policy to callers whose role is below the one that may see PII
+type Masker struct {
+	policy Policy
+	role   models.Role
+}
+
+// NewMasker masks data for callers below role
+func NewMasker(policy Policy, role models.Role) *Masker {
+	return &Masker{policy: policy, role: role}
+}
+
+// CanSee reports whether the caller in ctx may see unmasked PII. A nil Masker masks nothing,
+// so Order and Summary pass data through unchanged.
+func (m *Masker) CanSee(ctx context.Context) bool {
+	if m == nil {
+		return true
+	}
+	p, ok := auth.FromContext(ctx)
+	return ok && p.Role.Allows(m.role)
+}
+
+// Order masks o unless the caller in ctx may see PII
+func (m *Masker) Order(ctx context.Context, o models.Order) models.Order {
+	if m.CanSee(ctx) {
+		return o
+	}
+	return m.policy.Order(o)
+}
+
+// Summary masks the feed summary unless the caller in ctx may see PII
+func (m *Masker) Summary(ctx context.Context, s feed.Summary) feed.Summary {
+	if m.CanSee(ctx) {
+		return s
+	}
+	s.CustomerID = m.policy["customer_id"].Apply(s.CustomerID)
+	return s
+}
+
+// ReplaceAttr masks PII in log records whatever the caller's role: orders logged as a value,
+// and attributes named like a policy field (e.g. slog.Group("delivery", "phone", ...)).
+func (m *Masker) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Any().(type) {
+	case models.Order:
+		return slog.Any(a.Key, m.policy.Order(v))
+	case *models.Order:
+		if v != nil {
+			masked := m.policy.Order(*v)
+			return slog.Any(a.Key, &masked)
+		}
+	case models.Delivery:
+		return slog.Any(a.Key, m.policy.Order(models.Order{Delivery: v}).Delivery)
+	}
+	if a.Value.Kind() != slog.KindString {
+		return a
+	}
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + a.Key
+	}
+	if st, ok := m.policy[key]; ok {
+		return slog.String(a.Key, st.Apply(a.Value.String()))
+	}
+	return a
+}
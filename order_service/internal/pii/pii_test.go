@@ -0,0 +1,169 @@
+package pii
+
+import (
+	"context"
+	"maps"
+	"order_service/internal/auth"
+	"order_service/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	withDefaults := func(overrides Policy) Policy {
+		p := DefaultPolicy()
+		maps.Copy(p, overrides)
+		return p
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want Policy
+		err  string
+	}{
+		{"empty", "", DefaultPolicy(), ""},
+		{"override", "delivery.city=full,customer_id=partial", withDefaults(Policy{"delivery.city": Full, "customer_id": Partial}), ""},
+		{"spaces and empty entries", " delivery.phone = none ,, ", withDefaults(Policy{"delivery.phone": Keep}), ""},
+		{"last entry wins", "delivery.zip=partial,delivery.zip=none", withDefaults(Policy{"delivery.zip": Keep}), ""},
+		{"no strategy", "delivery.phone", nil, "is not field=strategy"},
+		{"no field", "=full", nil, "unknown field"},
+		{"unknown field", "delivery.ssn=full", nil, "unknown field"},
+		{"unknown strategy", "delivery.phone=hash", nil, "unknown strategy"},
+		{"empty strategy", "delivery.phone=", nil, "unknown strategy"},
+		{"one bad entry", "delivery.city=full,delivery.phone=hash", nil, "unknown strategy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(tt.in)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Fatalf("policy %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrategyApply(t *testing.T) {
+	tests := []struct {
+		strategy Strategy
+		in       string
+		want     string
+	}{
+		{Keep, "Ivan", "Ivan"},
+		{Full, "Lenina 1", "***"},
+		{Full, "", ""},
+		{Partial, "Ivan", "I***"},
+		{Partial, "Юлия", "Ю***"},
+		{Partial, "", ""},
+		{Phone, "+79001234567", "+7***4567"},
+		{Phone, "+7900123", "+7***0123"},
+		{Phone, "123456", "***"},
+		{Phone, "+7(900)", "+7***900)"},
+		{Email, "ivan@mail.ru", "i***@mail.ru"},
+		{Email, "ivan", "i***"},
+		{Email, "@mail.ru", "@***"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy)+"/"+tt.in, func(t *testing.T) {
+			if got := tt.strategy.Apply(tt.in); got != tt.want {
+				t.Fatalf("%q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func testOrder() models.Order {
+	return models.Order{
+		OrderUID:   "b563feb7b2b84b6test",
+		CustomerID: "test",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+	}
+}
+
+func TestPolicyOrder(t *testing.T) {
+	o := testOrder()
+	want := models.Delivery{
+		Name:    "T***",
+		Phone:   "+9***0000",
+		Zip:     "***",
+		City:    "Kiryat Mozkin",
+		Address: "***",
+		Region:  "Kraiot",
+		Email:   "t***@gmail.com",
+	}
+
+	got := DefaultPolicy().Order(o)
+	if got.Delivery != want {
+		t.Fatalf("delivery %+v, want %+v", got.Delivery, want)
+	}
+	if got.CustomerID != o.CustomerID || got.OrderUID != o.OrderUID {
+		t.Fatalf("fields outside the policy changed: %+v", got)
+	}
+	if o.Delivery != testOrder().Delivery {
+		t.Fatalf("the original order was masked: %+v", o.Delivery)
+	}
+
+	custom := Policy{"delivery.phone": Full, "customer_id": Partial}
+	got = custom.Order(o)
+	if got.Delivery.Phone != mask || got.CustomerID != "t***" || got.Delivery.Name != o.Delivery.Name {
+		t.Fatalf("a partial policy masked %+v", got)
+	}
+}
+
+// TestMaskerCanSee checks the PII_ROLE cutoff: callers at or above the role see everything
+func TestMaskerCanSee(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   models.Role
+		caller *auth.Principal
+		sees   bool
+	}{
+		{"reader below writer", models.RoleWriter, &auth.Principal{Role: models.RoleReader}, false},
+		{"writer at writer", models.RoleWriter, &auth.Principal{Role: models.RoleWriter}, true},
+		{"admin above writer", models.RoleWriter, &auth.Principal{Role: models.RoleAdmin}, true},
+		{"writer below admin", models.RoleAdmin, &auth.Principal{Role: models.RoleWriter}, false},
+		{"reader at reader", models.RoleReader, &auth.Principal{Role: models.RoleReader}, true},
+		{"unknown role", models.RoleReader, &auth.Principal{Role: "root"}, false},
+		{"no caller", models.RoleReader, nil, false},
+		{"anonymous", models.RoleAdmin, &auth.Anonymous, true},
+		{"cli export with pii", models.RoleAdmin, &auth.CLIExport, true},
+	}
+	o := testOrder()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.caller != nil {
+				ctx = auth.WithPrincipal(ctx, *tt.caller)
+			}
+			m := NewMasker(DefaultPolicy(), tt.role)
+			if got := m.CanSee(ctx); got != tt.sees {
+				t.Fatalf("CanSee %v, want %v", got, tt.sees)
+			}
+			if masked := m.Order(ctx, o).Delivery != o.Delivery; masked == tt.sees {
+				t.Fatalf("Order masked %v for a caller that can see PII %v", masked, tt.sees)
+			}
+		})
+	}
+
+	var m *Masker
+	if !m.CanSee(context.Background()) || m.Order(context.Background(), o).Delivery != o.Delivery {
+		t.Fatal("a nil masker must pass orders through")
+	}
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"order_service/internal/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Operation names passed to a Fault
+const (
+	OpSet     = "set"
+	OpGet     = "get"
+	OpGetMany = "get_many"
+)
+
+// Fault runs before every cache operation, a non-nil error fails the operation with it.
+// It may also sleep to simulate a slow cache.
+type Fault func(ctx context.Context, op string) error
+
+// FailOn returns a Fault failing the given operations (all of them when none are given) with err
+func FailOn(err error, ops ...string) Fault {
+	return func(_ context.Context, op string) error {
+		if len(ops) == 0 || slices.Contains(ops, op) {
+			return err
+		}
+		return nil
+	}
+}
+
+type memoryEntry struct {
+	order   models.Order
+	expires time.Time
+}
+
+// OrderCacheMemory keeps orders in a map with a TTL, for tests and the dependency-free dev mode.
+// Expired entries are dropped when they are read or by Cleanup.
+type OrderCacheMemory struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+	ttl     time.Duration
+	now     func() time.Time
+	fault   Fault
+}
+
+// NewOrderCacheMemory returns a cache keeping entries for ttl, 0 keeps them forever
+func NewOrderCacheMemory(ttl time.Duration) *OrderCacheMemory {
+	return &OrderCacheMemory{entries: map[string]memoryEntry{}, ttl: ttl, now: time.Now}
+}
+
+// WithClock replaces time.Now, so tests can expire entries without sleeping
+func (c *OrderCacheMemory) WithClock(now func() time.Time) *OrderCacheMemory {
+	c.now = now
+	return c
+}
+
+// SetFault installs f for the following operations, nil removes it
+func (c *OrderCacheMemory) SetFault(f Fault) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fault = f
+}
+
+func (c *OrderCacheMemory) inject(ctx context.Context, op string) error {
+	c.mu.RLock()
+	f := c.fault
+	c.mu.RUnlock()
+	if f == nil {
+		return ctx.Err()
+	}
+	if err := f(ctx, op); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Len is the number of entries, expired ones included until they are dropped
+func (c *OrderCacheMemory) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+func (c *OrderCacheMemory) Set(ctx context.Context, id string, order models.Order) error {
+	if err := c.inject(ctx, OpSet); err != nil {
+		return err
+	}
+	e := memoryEntry{order: order}
+	e.order.Items = slices.Clone(order.Items)
+	if c.ttl > 0 {
+		e.expires = c.now().Add(c.ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[id] = e
+	return nil
+}
+
+func (c *OrderCacheMemory) Get(ctx context.Context, id string) (models.Order, bool, error) {
+	if err := c.inject(ctx, OpGet); err != nil {
+		return models.Order{}, false, err
+	}
+	o, ok := c.lookup(id, c.now())
+	return o, ok, nil
+}
+
+// GetMany returns the cached orders by id, misses are simply absent from the map
+func (c *OrderCacheMemory) GetMany(ctx context.Context, ids []string) (map[string]models.Order, error) {
+	if err := c.inject(ctx, OpGetMany); err != nil {
+		return nil, err
+	}
+	now := c.now()
+	found := make(map[string]models.Order, len(ids))
+	for _, id := range ids {
+		if o, ok := c.lookup(id, now); ok {
+			found[id] = o
+		}
+	}
+	return found, nil
+}
+
+func (c *OrderCacheMemory) lookup(id string, now time.Time) (models.Order, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[id]
+	c.mu.RUnlock()
+	if !ok {
+		return models.Order{}, false
+	}
+	if e.expired(now) {
+		c.mu.Lock()
+		//it may have been set again since it was read
+		if e, ok := c.entries[id]; ok && e.expired(now) {
+			delete(c.entries, id)
+		}
+		c.mu.Unlock()
+		return models.Order{}, false
+	}
+	o := e.order
+	o.Items = slices.Clone(e.order.Items)
+	return o, true
+}
+
+// Cleanup drops expired entries every interval until ctx is done
+func (c *OrderCacheMemory) Cleanup(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := c.now()
+			c.mu.Lock()
+			for id, e := range c.entries {
+				if e.expired(now) {
+					delete(c.entries, id)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
@@ -0,0 +1,45 @@
+package cache_test
+
+import (
+	"context"
+	"order_service/internal/models"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/cache"
+	"order_service/internal/ports/cachetest"
+	"testing"
+	"time"
+)
+
+func TestOrderCacheMemory(t *testing.T) {
+	cachetest.Run(t, func(t *testing.T) ports.OrderCache {
+		return cache.NewOrderCacheMemory(time.Hour)
+	})
+}
+
+func TestOrderCacheMemoryExpires(t *testing.T) {
+	now := time.Now()
+	c := cache.NewOrderCacheMemory(time.Minute).WithClock(func() time.Time { return now })
+	ctx := context.Background()
+	if err := c.Set(ctx, "a", testOrder("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(59 * time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("entry expired before its ttl")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatal("entry served after its ttl")
+	}
+	if found, _ := c.GetMany(ctx, []string{"a"}); len(found) != 0 {
+		t.Fatal("GetMany served an expired entry")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expired entry is still held, %d entries", c.Len())
+	}
+}
+
+func testOrder(id string) models.Order {
+	return models.Order{OrderUID: id, Items: []models.Item{{Name: "item"}}}
+}
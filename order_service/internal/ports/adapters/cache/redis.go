@@ -2,22 +2,38 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"order_service/internal/models"
+	"order_service/internal/tracing"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type OrderCacheRedis struct {
 	client *redis.Client
+	ttl    time.Duration
 }
 
 func NewOrderCacheRedis(client *redis.Client) *OrderCacheRedis {
-	return &OrderCacheRedis{client: client}
+	return &OrderCacheRedis{client: client, ttl: time.Hour}
 }
 
-func (c *OrderCacheRedis) Set(ctx context.Context, id string, order models.Order) error {
-	err := c.client.Set(ctx, id, order, time.Hour).Err()
+// WithTTL sets how long orders stay cached, 0 keeps them until redis evicts them
+func (c *OrderCacheRedis) WithTTL(ttl time.Duration) *OrderCacheRedis {
+	c.ttl = ttl
+	return c
+}
+
+func (c *OrderCacheRedis) Set(ctx context.Context, id string, order models.Order) (err error) {
+	ctx, span := tracing.Start(ctx, "cache set", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "redis"), attribute.String("order_uid", id),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	err = c.client.Set(ctx, id, order, c.ttl).Err()
 	if err != nil {
 		return err
 	}
@@ -25,10 +41,50 @@ func (c *OrderCacheRedis) Set(ctx context.Context, id string, order models.Order
 }
 
 func (c *OrderCacheRedis) Get(ctx context.Context, id string) (models.Order, bool, error) {
+	ctx, span := tracing.Start(ctx, "cache get", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "redis"), attribute.String("order_uid", id),
+	))
 	var order models.Order
 	err := c.client.Get(ctx, id).Scan(&order)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if errors.Is(err, redis.Nil) {
+		//a missing key is a miss, not a failure
+		tracing.End(span, nil)
+		return models.Order{}, false, nil
+	}
+	tracing.End(span, err)
 	if err != nil {
 		return models.Order{}, false, err
 	}
 	return order, true, nil
 }
+
+// GetMany fetches all ids with a single MGET, entries that fail to decode count as misses
+func (c *OrderCacheRedis) GetMany(ctx context.Context, ids []string) (found map[string]models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "cache mget", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "redis"), attribute.Int("cache.keys", len(ids)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	found = make(map[string]models.Order, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+	vals, err := c.client.MGet(ctx, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var order models.Order
+		if err := order.UnmarshalBinary([]byte(s)); err != nil {
+			continue
+		}
+		found[ids[i]] = order
+	}
+	span.SetAttributes(attribute.Int("cache.hits", len(found)))
+	return found, nil
+}
@@ -0,0 +1,34 @@
+package cache_test
+
+import (
+	"context"
+	"order_service/internal/config"
+	"order_service/internal/infra/redis"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/cache"
+	"order_service/internal/ports/cachetest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestOrderCacheRedis runs against the redis in TEST_REDIS_URL, e.g. redis://localhost:6379/15.
+// Entries expire after a minute.
+func TestOrderCacheRedis(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL is not set")
+	}
+	client, err := redis.New(config.RedisConfig{ConnString: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	cachetest.Run(t, func(t *testing.T) ports.OrderCache {
+		return cache.NewOrderCacheRedis(client).WithTTL(time.Minute)
+	})
+}
@@ -0,0 +1,133 @@
+package keystore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"order_service/internal/models"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// KeyStoreFile keeps the keys in a JSON file, for deployments without a keys table.
+// The file is re-read when it changes on disk, so keys edited by the CLI apply without a restart.
+type KeyStoreFile struct {
+	path string
+
+	mu      sync.Mutex
+	keys    []models.APIKey
+	modTime time.Time
+}
+
+func NewKeyStoreFile(path string) *KeyStoreFile {
+	return &KeyStoreFile{path: path}
+}
+
+func (s *KeyStoreFile) GetByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return models.APIKey{}, err
+	}
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return models.APIKey{}, ErrNotFound
+}
+
+func (s *KeyStoreFile) Create(ctx context.Context, key models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.keys = append(s.keys, key)
+	return s.save()
+}
+
+func (s *KeyStoreFile) List(ctx context.Context) ([]models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return append([]models.APIKey(nil), s.keys...), nil
+}
+
+func (s *KeyStoreFile) Revoke(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i, k := range s.keys {
+		if k.ID == id && !k.Revoked() {
+			now := time.Now().UTC()
+			s.keys[i].RevokedAt = &now
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// load re-reads the file when its modification time changed, a missing file is an empty store
+func (s *KeyStoreFile) load() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.keys, s.modTime = nil, time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat keys file: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("read keys file: %w", err)
+	}
+	var keys []models.APIKey
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return fmt.Errorf("parse keys file: %w", err)
+	}
+	s.keys, s.modTime = keys, info.ModTime()
+	return nil
+}
+
+// save writes to a temp file and renames it, so readers never see a half-written file
+func (s *KeyStoreFile) save() error {
+	b, err := json.MarshalIndent(s.keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode keys: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".keys-*.json")
+	if err != nil {
+		return fmt.Errorf("create temp keys file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write keys file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write keys file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return fmt.Errorf("chmod keys file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replace keys file: %w", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
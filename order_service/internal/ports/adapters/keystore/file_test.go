@@ -0,0 +1,153 @@
+package keystore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKey(id string) models.APIKey {
+	return models.APIKey{ID: id, Name: "key " + id, Role: models.RoleReader, Hash: "hash-" + id, CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestKeyStoreFileRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s := NewKeyStoreFile(path)
+
+	keys, err := s.List(ctx)
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("a missing file should be an empty store: %v, %v", keys, err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := s.Create(ctx, testKey(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Revoke(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		fn   func() error
+	}{
+		{"revoke twice", errdef.ErrNotFound, func() error { return s.Revoke(ctx, "a") }},
+		{"revoke unknown", errdef.ErrNotFound, func() error { return s.Revoke(ctx, "c") }},
+		{"get unknown", errdef.ErrNotFound, func() error { _, err := s.GetByHash(ctx, "hash-c"); return err }},
+		{"get revoked", nil, func() error { _, err := s.GetByHash(ctx, "hash-a"); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, tt.err) {
+				t.Fatalf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	//a new store only sees what was saved to the file
+	keys, err = NewKeyStoreFile(path).List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != "a" || keys[1].ID != "b" {
+		t.Fatalf("keys %+v, want a and b", keys)
+	}
+	if !keys[0].Revoked() || keys[1].Revoked() {
+		t.Fatalf("only a should be revoked: %+v", keys)
+	}
+	got, err := NewKeyStoreFile(path).GetByHash(ctx, "hash-b")
+	if err != nil || got.Name != "key b" || got.Role != models.RoleReader || !got.CreatedAt.Equal(testKey("b").CreatedAt) {
+		t.Fatalf("GetByHash: %+v, %v", got, err)
+	}
+}
+
+// TestKeyStoreFileReload checks that a file changed by another process (the keys CLI) is picked up
+func TestKeyStoreFileReload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s := NewKeyStoreFile(path)
+	if err := s.Create(ctx, testKey("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByHash(ctx, "hash-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewKeyStoreFile(path).Revoke(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	//mtimes can be coarse, make sure this write is seen as a change
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetByHash(ctx, "hash-a")
+	if err != nil || !got.Revoked() {
+		t.Fatalf("the revocation was not reloaded: %+v, %v", got, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByHash(ctx, "hash-a"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Fatalf("a removed file should be an empty store: %v", err)
+	}
+}
+
+func TestKeyStoreFileSave(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.json")
+	s := NewKeyStoreFile(path)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := s.Create(ctx, testKey(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "keys.json" {
+		t.Fatalf("temp files were left behind: %v", entries)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("keys file mode %o, want 600", perm)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []models.APIKey
+	if err := json.Unmarshal(b, &keys); err != nil || len(keys) != 3 {
+		t.Fatalf("keys file %s: %v", b, err)
+	}
+}
+
+func TestKeyStoreFileMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`{"id": "a"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewKeyStoreFile(path)
+	if _, err := s.GetByHash(context.Background(), "hash-a"); err == nil || errors.Is(err, errdef.ErrNotFound) {
+		t.Fatalf("err %v, want a parse error", err)
+	}
+	//a broken file must not be overwritten with only the new key
+	if err := s.Create(context.Background(), testKey("b")); err == nil {
+		t.Fatal("created a key on top of a malformed file")
+	}
+}
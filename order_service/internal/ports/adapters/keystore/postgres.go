@@ -0,0 +1,78 @@
+package keystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNotFound = fmt.Errorf("api key %w", errdef.ErrNotFound)
+
+type KeyStorePostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewKeyStorePostgres(pool *pgxpool.Pool) *KeyStorePostgres {
+	return &KeyStorePostgres{pool: pool}
+}
+
+func (s *KeyStorePostgres) GetByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	const sql = `SELECT id, name, role, key_hash, created_at, revoked_at FROM api_keys WHERE key_hash = $1`
+	var k models.APIKey
+	err := s.pool.QueryRow(ctx, sql, hash).Scan(&k.ID, &k.Name, &k.Role, &k.Hash, &k.CreatedAt, &k.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.APIKey{}, ErrNotFound
+		}
+		return models.APIKey{}, fmt.Errorf("get api key: %w", err)
+	}
+	return k, nil
+}
+
+func (s *KeyStorePostgres) Create(ctx context.Context, key models.APIKey) error {
+	const sql = `INSERT INTO api_keys (id, name, role, key_hash, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.pool.Exec(ctx, sql, key.ID, key.Name, key.Role, key.Hash, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create api key: %w", err)
+	}
+	return nil
+}
+
+func (s *KeyStorePostgres) List(ctx context.Context) ([]models.APIKey, error) {
+	const sql = `SELECT id, name, role, key_hash, created_at, revoked_at FROM api_keys ORDER BY created_at`
+	rows, err := s.pool.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.Role, &k.Hash, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("api key rows: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *KeyStorePostgres) Revoke(ctx context.Context, id string) error {
+	const sql = `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+	tag, err := s.pool.Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
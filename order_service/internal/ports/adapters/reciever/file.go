@@ -0,0 +1,370 @@
+package reciever
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"order_service/internal/errdef"
+	"order_service/internal/logging"
+	"order_service/internal/ports"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxLineSize bounds one NDJSON record, orders with thousands of items still fit
+const maxLineSize = 16 << 20
+
+// ErrInterrupted is returned by ReceiverFile.Run when ctx was cancelled before the end of
+// the file or records were aborted, the checkpoint lets the next run continue
+var ErrInterrupted = errors.New("import interrupted")
+
+// FileOptions tunes a ReceiverFile, zero values pick the defaults
+type FileOptions struct {
+	// Concurrency is how many records are processed at once, default 1
+	Concurrency int
+	// RejectsPath receives one NDJSON report per record that failed, empty disables it.
+	// A resumed import keeps the reports of the lines before the checkpoint, otherwise it is overwritten.
+	RejectsPath string
+	// CheckpointPath keeps the progress so an interrupted import resumes, empty disables it
+	CheckpointPath string
+	// CheckpointEvery writes the checkpoint after this many records, default 1000
+	CheckpointEvery int
+}
+
+// FileSummary is the outcome of one import
+type FileSummary struct {
+	Lines         int `json:"lines"`
+	Resumed       int `json:"resumed"`
+	Saved         int `json:"saved"`
+	Duplicates    int `json:"duplicates"`
+	Blank         int `json:"blank"`
+	DecodeFailed  int `json:"decode_failed"`
+	ProcessFailed int `json:"process_failed"`
+	// Aborted records were cancelled at the shutdown deadline, the next run imports them again
+	Aborted  int           `json:"aborted"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (s FileSummary) Failed() int {
+	return s.DecodeFailed + s.ProcessFailed
+}
+
+// checkpoint is the last line up to which every record was handled
+type checkpoint struct {
+	Source  string    `json:"source"`
+	Size    int64     `json:"size"`
+	Line    int       `json:"line"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+type reject struct {
+	Line   int             `json:"line"`
+	Stage  string          `json:"stage"`
+	Error  string          `json:"error"`
+	Record json.RawMessage `json:"record,omitempty"`
+	Raw    string          `json:"raw,omitempty"`
+}
+
+type fileRecord struct {
+	line int
+	data []byte
+}
+
+// ReceiverFile reads NDJSON records from a file, plain or gzip, and hands them to the
+// same kind of handle func as ReceiverKafka. Already stored orders count as duplicates,
+// so an import can be repeated safely.
+type ReceiverFile[M any] struct {
+	path     string
+	decodeFn func([]byte) (M, error)
+	opts     FileOptions
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	summary FileSummary
+	rejects *json.Encoder
+	// done tracks finished lines above the checkpoint, they can finish out of order
+	done      map[int]bool
+	watermark int
+	sinceSave int
+	size      int64
+}
+
+func NewRecieverFile[M any](path string, f func([]byte) (M, error), opts FileOptions, logger *slog.Logger) *ReceiverFile[M] {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.CheckpointEvery < 1 {
+		opts.CheckpointEvery = 1000
+	}
+	return &ReceiverFile[M]{path: path, decodeFn: f, opts: opts, logger: logger.With("component", "reciever_file", "source", path)}
+}
+
+// Run imports the file. Cancelling ctx stops reading, records already handed to workers
+// are finished and the checkpoint is written, so the next run continues after them.
+func (r *ReceiverFile[M]) Run(ctx context.Context, handle func(context.Context, M) error) error {
+	start := time.Now()
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("open import file: %w", err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		r.size = info.Size()
+	}
+
+	if err := r.loadCheckpoint(); err != nil {
+		return err
+	}
+	if r.watermark > 0 {
+		r.logger.Info("resuming import from checkpoint", "line", r.watermark)
+	}
+	r.done = make(map[int]bool)
+	r.summary.Resumed = r.watermark
+
+	if r.opts.RejectsPath != "" {
+		rf, err := r.openRejects()
+		if err != nil {
+			return err
+		}
+		defer rf.Close()
+		r.rejects = json.NewEncoder(rf)
+	}
+
+	input, err := decompress(f)
+	if err != nil {
+		return err
+	}
+
+	records := make(chan fileRecord, r.opts.Concurrency*2)
+	var wg sync.WaitGroup
+	//records in flight must finish even after ctx is cancelled like ReceiverKafka does, handle bounds them
+	procCtx := context.WithoutCancel(ctx)
+	for range r.opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rec := range records {
+				r.process(procCtx, rec, handle)
+			}
+		}()
+	}
+
+	readErr := r.read(ctx, input, records)
+	close(records)
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Duration = time.Since(start)
+	if readErr == nil && r.summary.Aborted > 0 {
+		//the whole file was read, but not every record in it was imported
+		readErr = fmt.Errorf("%w: %d records aborted", ErrInterrupted, r.summary.Aborted)
+	}
+	if err := r.saveCheckpoint(); err != nil {
+		return errors.Join(readErr, err)
+	}
+	return readErr
+}
+
+// Summary is the outcome of the last Run
+func (r *ReceiverFile[M]) Summary() FileSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary
+}
+
+func (r *ReceiverFile[M]) read(ctx context.Context, input io.Reader, records chan<- fileRecord) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		r.mu.Lock()
+		r.summary.Lines = line
+		r.mu.Unlock()
+		if line <= r.summary.Resumed {
+			continue
+		}
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			r.finish(line, func(s *FileSummary) { s.Blank++ })
+			continue
+		}
+		select {
+		case records <- fileRecord{line: line, data: bytes.Clone(data)}:
+		case <-ctx.Done():
+		}
+		//checked after the send too, a free slot in records must not hide the cancellation
+		if err := ctx.Err(); err != nil {
+			r.logger.Info("import interrupted", "line", line)
+			return fmt.Errorf("%w at line %d: %w", ErrInterrupted, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read import file at line %d: %w", line+1, err)
+	}
+	return nil
+}
+
+func (r *ReceiverFile[M]) process(ctx context.Context, rec fileRecord, handle func(context.Context, M) error) {
+	ctx = logging.WithCorrelationID(ctx, filepath.Base(r.path)+"-"+strconv.Itoa(rec.line))
+
+	m, err := r.decodeFn(rec.data)
+	if err != nil {
+		r.logger.WarnContext(ctx, "failed to decode record", "line", rec.line, "error", err)
+		r.reject(rec, "decode", err)
+		r.finish(rec.line, func(s *FileSummary) { s.DecodeFailed++ })
+		return
+	}
+
+	err = handle(ctx, m)
+	switch {
+	case err == nil:
+		r.finish(rec.line, func(s *FileSummary) { s.Saved++ })
+	case errors.Is(err, errdef.ErrAlreadyExists):
+		r.finish(rec.line, func(s *FileSummary) { s.Duplicates++ })
+	case errors.Is(err, ports.ErrAborted):
+		//left unfinished, the checkpoint stays before it and the next run imports it again
+		r.logger.WarnContext(ctx, "record aborted", "line", rec.line, "error", err)
+		r.mu.Lock()
+		r.summary.Aborted++
+		r.mu.Unlock()
+	default:
+		r.logger.WarnContext(ctx, "failed to process record", "line", rec.line, "error", err)
+		r.reject(rec, "process", err)
+		r.finish(rec.line, func(s *FileSummary) { s.ProcessFailed++ })
+	}
+}
+
+func (r *ReceiverFile[M]) reject(rec fileRecord, stage string, cause error) {
+	if r.rejects == nil {
+		return
+	}
+	rep := reject{Line: rec.line, Stage: stage, Error: cause.Error()}
+	if json.Valid(rec.data) {
+		rep.Record = rec.data
+	} else {
+		rep.Raw = string(rec.data)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.rejects.Encode(rep); err != nil {
+		r.logger.Error("failed to write reject", "line", rec.line, "error", err)
+	}
+}
+
+// openRejects opens the rejects file for this run. The lines after the checkpoint are
+// processed again, so their rejects from an earlier run are dropped instead of repeated;
+// without a checkpoint the file starts over.
+func (r *ReceiverFile[M]) openRejects() (*os.File, error) {
+	var kept [][]byte
+	if r.watermark > 0 {
+		b, err := os.ReadFile(r.opts.RejectsPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read rejects file: %w", err)
+		}
+		for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+			var rep reject
+			if json.Unmarshal(line, &rep) == nil && rep.Line <= r.watermark {
+				kept = append(kept, line)
+			}
+		}
+	}
+	f, err := os.OpenFile(r.opts.RejectsPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open rejects file: %w", err)
+	}
+	for _, line := range kept {
+		if _, err := f.Write(line); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("write rejects file: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// finish records a handled line and moves the checkpoint past every line finished so far
+func (r *ReceiverFile[M]) finish(line int, count func(*FileSummary)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count(&r.summary)
+	r.done[line] = true
+	for r.done[r.watermark+1] {
+		delete(r.done, r.watermark+1)
+		r.watermark++
+		r.sinceSave++
+	}
+	if r.sinceSave >= r.opts.CheckpointEvery {
+		if err := r.saveCheckpoint(); err != nil {
+			r.logger.Error("failed to write checkpoint", "error", err)
+		}
+	}
+}
+
+func (r *ReceiverFile[M]) loadCheckpoint() error {
+	if r.opts.CheckpointPath == "" {
+		return nil
+	}
+	b, err := os.ReadFile(r.opts.CheckpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return fmt.Errorf("parse checkpoint %s: %w", r.opts.CheckpointPath, err)
+	}
+	abs, _ := filepath.Abs(r.path)
+	if cp.Source != abs || cp.Size != r.size {
+		return fmt.Errorf("checkpoint %s belongs to %s (%d bytes), not %s (%d bytes): remove it to start over",
+			r.opts.CheckpointPath, cp.Source, cp.Size, abs, r.size)
+	}
+	r.watermark = cp.Line
+	return nil
+}
+
+// saveCheckpoint must be called with r.mu held
+func (r *ReceiverFile[M]) saveCheckpoint() error {
+	r.sinceSave = 0
+	if r.opts.CheckpointPath == "" {
+		return nil
+	}
+	abs, _ := filepath.Abs(r.path)
+	b, err := json.Marshal(checkpoint{Source: abs, Size: r.size, Line: r.watermark, SavedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	tmp := r.opts.CheckpointPath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, r.opts.CheckpointPath); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// decompress sniffs the gzip magic bytes, so .gz files need no flag
+func decompress(f io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip import file: %w", err)
+		}
+		return zr, nil
+	}
+	return br, nil
+}
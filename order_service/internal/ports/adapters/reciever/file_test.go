@@ -0,0 +1,335 @@
+package reciever
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order_service/internal/logging"
+	"order_service/internal/ports"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type record struct {
+	ID string `json:"id"`
+}
+
+func decodeRecord(b []byte) (record, error) {
+	var r record
+	err := json.Unmarshal(b, &r)
+	return r, err
+}
+
+// writeLines writes one line per id, "bad" ids fail handle and "{" ids fail decoding
+func writeLines(t *testing.T, path string, ids ...string) {
+	t.Helper()
+	var b strings.Builder
+	for _, id := range ids {
+		if id == "{" {
+			b.WriteString("{\n")
+			continue
+		}
+		fmt.Fprintf(&b, "{\"id\": %q}\n", id)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handled records the ids handle saw, ids starting with bad fail
+type handled struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (h *handled) handle(_ context.Context, r record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ids = append(h.ids, r.ID)
+	if strings.HasPrefix(r.ID, "bad") {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func readRejects(t *testing.T, path string) []int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rep reject
+		if err := json.Unmarshal(scanner.Bytes(), &rep); err != nil {
+			t.Fatalf("reject %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, rep.Line)
+	}
+	slices.Sort(lines)
+	return lines
+}
+
+func TestFileImport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.ndjson")
+	writeLines(t, path, "a", "bad1", "{", "b")
+
+	var h handled
+	r := NewRecieverFile(path, decodeRecord, FileOptions{Concurrency: 2, RejectsPath: filepath.Join(dir, "rejects.ndjson")}, logging.Nop())
+	if err := r.Run(context.Background(), h.handle); err != nil {
+		t.Fatal(err)
+	}
+	s := r.Summary()
+	if s.Lines != 4 || s.Saved != 2 || s.ProcessFailed != 1 || s.DecodeFailed != 1 {
+		t.Fatalf("summary %+v", s)
+	}
+	if got := readRejects(t, filepath.Join(dir, "rejects.ndjson")); !slices.Equal(got, []int{2, 3}) {
+		t.Fatalf("rejected lines %v, want [2 3]", got)
+	}
+}
+
+func TestFileImportGzip(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "orders.ndjson")
+	writeLines(t, plain, "a", "b", "c")
+	b, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//no .gz suffix, the magic bytes decide
+	zipped := filepath.Join(dir, "orders.ndjson.bin")
+	f, err := os.Create(zipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write(b)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var h handled
+	r := NewRecieverFile(zipped, decodeRecord, FileOptions{}, logging.Nop())
+	if err := r.Run(context.Background(), h.handle); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(h.ids, []string{"a", "b", "c"}) {
+		t.Fatalf("handled %v", h.ids)
+	}
+}
+
+func TestFileImportResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.ndjson")
+	var ids []string
+	for i := range 50 {
+		ids = append(ids, fmt.Sprint(i))
+	}
+	writeLines(t, path, ids...)
+	opts := FileOptions{CheckpointPath: filepath.Join(dir, "checkpoint.json"), CheckpointEvery: 1}
+
+	//the first run is interrupted after a few records
+	var first handled
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRecieverFile(path, decodeRecord, opts, logging.Nop())
+	err := r.Run(ctx, func(ctx context.Context, rec record) error {
+		if rec.ID == "5" {
+			cancel()
+		}
+		return first.handle(ctx, rec)
+	})
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("interrupted run: got %v, want ErrInterrupted", err)
+	}
+
+	var second handled
+	r = NewRecieverFile(path, decodeRecord, opts, logging.Nop())
+	if err := r.Run(context.Background(), second.handle); err != nil {
+		t.Fatal(err)
+	}
+	if s := r.Summary(); s.Resumed != len(first.ids) {
+		t.Fatalf("resumed after %d lines, the first run handled %d", s.Resumed, len(first.ids))
+	}
+	got := append(slices.Clone(first.ids), second.ids...)
+	slices.Sort(got)
+	want := slices.Clone(ids)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("every record must be handled exactly once, got %v", got)
+	}
+}
+
+// TestFileImportResumeRejects resumes after a crash that left rejects past the checkpoint
+func TestFileImportResumeRejects(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.ndjson")
+	writeLines(t, path, "bad1", "a", "b", "bad4", "c")
+	rejects := filepath.Join(dir, "rejects.ndjson")
+	opts := FileOptions{RejectsPath: rejects, CheckpointPath: filepath.Join(dir, "checkpoint.json")}
+
+	r := NewRecieverFile(path, decodeRecord, opts, logging.Nop())
+	if err := r.Run(context.Background(), (&handled{}).handle); err != nil {
+		t.Fatal(err)
+	}
+	//the crashed run had checkpointed line 2 only
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := json.Marshal(checkpoint{Source: abs, Size: info.Size(), Line: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(opts.CheckpointPath, cp, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var h handled
+	r = NewRecieverFile(path, decodeRecord, opts, logging.Nop())
+	if err := r.Run(context.Background(), h.handle); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(h.ids, []string{"b", "bad4", "c"}) {
+		t.Fatalf("handled %v, want the lines after the checkpoint", h.ids)
+	}
+	if got := readRejects(t, rejects); !slices.Equal(got, []int{1, 4}) {
+		t.Fatalf("rejected lines %v, want each of [1 4] once", got)
+	}
+
+	//without a checkpoint the rejects start over
+	os.Remove(opts.CheckpointPath)
+	r = NewRecieverFile(path, decodeRecord, FileOptions{RejectsPath: rejects}, logging.Nop())
+	if err := r.Run(context.Background(), (&handled{}).handle); err != nil {
+		t.Fatal(err)
+	}
+	if got := readRejects(t, rejects); !slices.Equal(got, []int{1, 4}) {
+		t.Fatalf("rejected lines %v, want each of [1 4] once", got)
+	}
+}
+
+func TestFileCheckpointMismatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.ndjson")
+	writeLines(t, path, "a", "b")
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, cp := range map[string]checkpoint{
+		"other source": {Source: filepath.Join(dir, "other.ndjson"), Size: info.Size(), Line: 1},
+		"other size":   {Source: abs, Size: info.Size() + 1, Line: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cpPath := filepath.Join(t.TempDir(), "checkpoint.json")
+			b, err := json.Marshal(cp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(cpPath, b, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var h handled
+			r := NewRecieverFile(path, decodeRecord, FileOptions{CheckpointPath: cpPath}, logging.Nop())
+			err = r.Run(context.Background(), h.handle)
+			if err == nil || !strings.Contains(err.Error(), "remove it to start over") {
+				t.Fatalf("got %v, want a checkpoint mismatch", err)
+			}
+			if len(h.ids) != 0 {
+				t.Fatalf("records were handled: %v", h.ids)
+			}
+		})
+	}
+}
+
+func TestFileFinishOutOfOrder(t *testing.T) {
+	dir := t.TempDir()
+	cpPath := filepath.Join(dir, "checkpoint.json")
+	r := NewRecieverFile(filepath.Join(dir, "orders.ndjson"), decodeRecord, FileOptions{CheckpointPath: cpPath, CheckpointEvery: 1}, logging.Nop())
+	r.done = make(map[int]bool)
+	saved := func(s *FileSummary) { s.Saved++ }
+
+	for _, step := range []struct {
+		line, watermark int
+	}{
+		//lines 2 and 3 finish before line 1, the checkpoint can't move past a line in flight
+		{3, 0},
+		{2, 0},
+		{1, 3},
+		{5, 3},
+		{4, 5},
+	} {
+		r.finish(step.line, saved)
+		if r.watermark != step.watermark {
+			t.Fatalf("after line %d: watermark %d, want %d", step.line, r.watermark, step.watermark)
+		}
+	}
+	b, err := os.ReadFile(cpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if cp.Line != 5 || len(r.done) != 0 {
+		t.Fatalf("checkpoint at line %d with %d lines pending, want 5 and none", cp.Line, len(r.done))
+	}
+}
+
+// TestFileImportAbortedRecord checks an order aborted at the shutdown deadline is neither
+// rejected nor checkpointed, so the next run imports it
+func TestFileImportAbortedRecord(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.ndjson")
+	writeLines(t, path, "a", "aborted", "b")
+	rejects := filepath.Join(dir, "rejects.ndjson")
+	opts := FileOptions{RejectsPath: rejects, CheckpointPath: filepath.Join(dir, "checkpoint.json"), CheckpointEvery: 1}
+
+	var first handled
+	r := NewRecieverFile(path, decodeRecord, opts, logging.Nop())
+	err := r.Run(context.Background(), func(ctx context.Context, rec record) error {
+		if rec.ID == "aborted" {
+			return fmt.Errorf("%w: %w", ports.ErrAborted, context.Canceled)
+		}
+		return first.handle(ctx, rec)
+	})
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("got %v, want ErrInterrupted", err)
+	}
+	if s := r.Summary(); s.Aborted != 1 || s.Failed() != 0 {
+		t.Fatalf("summary %+v, want one aborted record and no failures", s)
+	}
+	if got := readRejects(t, rejects); len(got) != 0 {
+		t.Fatalf("rejected lines %v, want none", got)
+	}
+
+	var second handled
+	r = NewRecieverFile(path, decodeRecord, opts, logging.Nop())
+	if err := r.Run(context.Background(), second.handle); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(second.ids, []string{"aborted", "b"}) {
+		t.Fatalf("the resumed run handled %v, want the aborted record and the ones after it", second.ids)
+	}
+}
@@ -3,48 +3,143 @@ package reciever
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"order_service/internal/logging"
+	"order_service/internal/metrics"
+	"order_service/internal/ports"
+	"order_service/internal/tracing"
+	"strconv"
 
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ReceiverKafka[M any] struct {
 	kafkaReader *kafka.Reader
 	decodeFn    func([]byte) (M, error)
+	dlq         *kafka.Writer
+	logger      *slog.Logger
 }
 
-func NewRecieverKafka[M any](r *kafka.Reader, f func([]byte) (M, error)) *ReceiverKafka[M] {
-	return &ReceiverKafka[M]{kafkaReader: r, decodeFn: f}
+func NewRecieverKafka[M any](r *kafka.Reader, f func([]byte) (M, error), logger *slog.Logger) *ReceiverKafka[M] {
+	return &ReceiverKafka[M]{kafkaReader: r, decodeFn: f, logger: logger.With("component", "reciever_kafka")}
 }
 
+// WithDLQ makes the reciever publish messages it cannot decode or process to w
+// instead of only logging and skipping them
+func (r *ReceiverKafka[M]) WithDLQ(w *kafka.Writer) *ReceiverKafka[M] {
+	r.dlq = w
+	return r
+}
+
+// Run consumes messages until ctx is cancelled. Cancelling ctx only stops fetching:
+// a message that is already being processed is finished and committed first,
+// so an order is never half-saved or re-delivered because of a shutdown. handle is
+// expected to bound its own context, see service.OrderReciverService.WithAbort; a message
+// it aborts is returned as an error without committing it, so it is redelivered.
 func (r *ReceiverKafka[M]) Run(ctx context.Context, handle func(context.Context, M) error) error {
 
+	//processing must outlive the shutdown signal, handle bounds it with the caller's deadline
+	procCtx := context.WithoutCancel(ctx)
+
 	//will recieve event
 	for {
-		//read the message
-		msg, err := r.kafkaReader.ReadMessage(ctx)
-		log.Println("read the message")
+		//fetch the message, offsets are committed manually after processing
+		msg, err := r.kafkaReader.FetchMessage(ctx)
 		if err != nil {
-			if errors.Is(err, context.Canceled) {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 				return nil
 			}
 			return err
 		}
+		msgCtx := logging.WithCorrelationID(procCtx, correlationID(msg))
+		//continue the producer's trace when it sent a traceparent header
+		msgCtx, span := tracing.Start(tracing.ExtractKafka(msgCtx, &msg), msg.Topic+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				semconv.MessagingSystemKafka,
+				semconv.MessagingDestinationName(msg.Topic),
+				semconv.MessagingDestinationPartitionID(strconv.Itoa(msg.Partition)),
+				semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+				semconv.MessagingKafkaConsumerGroup(r.kafkaReader.Config().GroupID),
+			))
+		r.logger.DebugContext(msgCtx, "read the message", "partition", msg.Partition, "offset", msg.Offset)
 
-		//decode payload
-		m, err := r.decodeFn(msg.Value)
-		if err != nil {
-			log.Printf("falied to decode kafka message: %v", err)
-			continue
+		//an aborted message or one the DLQ didn't take is left uncommitted, it is redelivered after a restart
+		if err := r.process(msgCtx, msg, handle); err != nil {
+			tracing.End(span, err)
+			return err
 		}
-		//to-do try to valdiate
 
-		//process the order
-		err = handle(ctx, m)
+		err = r.kafkaReader.CommitMessages(msgCtx, msg)
+		tracing.End(span, err)
 		if err != nil {
-			log.Printf("failed to process the kafka order: %v", err)
-			continue
+			return fmt.Errorf("failed to commit kafka message: %w", err)
 		}
 	}
 
 }
+
+// process decodes and handles msg. An error means msg must not be committed: it was
+// aborted, or it failed and could not be dead-lettered.
+func (r *ReceiverKafka[M]) process(ctx context.Context, msg kafka.Message, handle func(context.Context, M) error) error {
+	//decode payload
+	m, err := r.decodeFn(msg.Value)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to decode kafka message", "partition", msg.Partition, "offset", msg.Offset, "error", err)
+		metrics.KafkaMessage(metrics.KafkaDecodeFailed)
+		trace.SpanFromContext(ctx).SetStatus(codes.Error, "decode failed")
+		return r.deadLetter(ctx, msg, metrics.KafkaDecodeFailed, err)
+	}
+	//process the order
+	err = handle(ctx, m)
+	if errors.Is(err, ports.ErrAborted) {
+		r.logger.WarnContext(ctx, "kafka order aborted, it is left for redelivery", "partition", msg.Partition, "offset", msg.Offset, "error", err)
+		trace.SpanFromContext(ctx).SetStatus(codes.Error, "aborted")
+		return err
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to process the kafka order", "partition", msg.Partition, "offset", msg.Offset, "error", err)
+		metrics.KafkaMessage(metrics.KafkaProcessFailed)
+		trace.SpanFromContext(ctx).SetStatus(codes.Error, "process failed")
+		return r.deadLetter(ctx, msg, metrics.KafkaProcessFailed, err)
+	}
+	metrics.KafkaMessage(metrics.KafkaProcessed)
+	return nil
+}
+
+// deadLetter copies msg to the DLQ topic with the failure reason in the headers.
+// Without a DLQ the message is only logged and skipped.
+func (r *ReceiverKafka[M]) deadLetter(ctx context.Context, msg kafka.Message, reason string, cause error) error {
+	if r.dlq == nil {
+		return nil
+	}
+	headers := append(msg.Headers[:len(msg.Headers):len(msg.Headers)],
+		kafka.Header{Key: "x-dlq-reason", Value: []byte(reason)},
+		kafka.Header{Key: "x-dlq-error", Value: []byte(cause.Error())},
+		kafka.Header{Key: "x-dlq-source-topic", Value: []byte(msg.Topic)},
+		kafka.Header{Key: "x-dlq-source-partition", Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: "x-dlq-source-offset", Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+	err := r.dlq.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	metrics.KafkaDLQ(err)
+	if err != nil {
+		return fmt.Errorf("failed to publish message %d/%d to the DLQ: %w", msg.Partition, msg.Offset, err)
+	}
+	r.logger.WarnContext(ctx, "message sent to the DLQ", "partition", msg.Partition, "offset", msg.Offset, "reason", reason)
+	return nil
+}
+
+// correlationID takes the producer's correlation id from the headers, or derives a stable one
+// from the message position so redeliveries of the same message log with the same id
+func correlationID(msg kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == logging.CorrelationKafkaHeader && len(h.Value) > 0 {
+			return string(h.Value)
+		}
+	}
+	return fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset)
+}
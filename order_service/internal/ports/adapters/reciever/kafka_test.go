@@ -0,0 +1,33 @@
+package reciever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order_service/internal/logging"
+	"order_service/internal/ports"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// TestKafkaAbortedMessageIsNotCommitted checks process fails an aborted message, which
+// makes Run return before CommitMessages, while a failed one without a DLQ is skipped
+func TestKafkaAbortedMessageIsNotCommitted(t *testing.T) {
+	r := NewRecieverKafka(nil, decodeRecord, logging.Nop())
+	msg := kafka.Message{Topic: "orders", Value: []byte(`{"id": "a"}`)}
+
+	err := r.process(context.Background(), msg, func(context.Context, record) error {
+		return fmt.Errorf("%w: %w", ports.ErrAborted, context.Canceled)
+	})
+	if !errors.Is(err, ports.ErrAborted) {
+		t.Fatalf("aborted message: got %v, want ports.ErrAborted so it stays uncommitted", err)
+	}
+
+	err = r.process(context.Background(), msg, func(context.Context, record) error {
+		return errors.New("rejected")
+	})
+	if err != nil {
+		t.Fatalf("failed message without a DLQ: got %v, want it skipped", err)
+	}
+}
@@ -0,0 +1,134 @@
+package reciever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"order_service/internal/logging"
+	"order_service/internal/metrics"
+	"order_service/internal/ports"
+	"sync"
+)
+
+// MemoryMessage is a message published to ReceiverMemory
+type MemoryMessage struct {
+	Key   string
+	Value []byte
+	// CorrelationID is logged with the message like the kafka header, derived from the offset when empty
+	CorrelationID string
+	Offset        int64
+}
+
+// DeadLetter is a message the reciever could not decode or process
+type DeadLetter struct {
+	Message MemoryMessage
+	// Reason is decode_failed or process_failed, like the x-dlq-reason kafka header
+	Reason string
+	Err    error
+}
+
+// ReceiverMemory is a local stand-in for kafka: published messages are consumed in order by Run,
+// with the same decode, process and dead letter steps as ReceiverKafka. For tests and the dev mode.
+type ReceiverMemory[M any] struct {
+	messages chan MemoryMessage
+	decodeFn func([]byte) (M, error)
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	offset    int64
+	processed int
+	dead      []DeadLetter
+}
+
+// NewRecieverMemory returns a reciever holding up to buffer unconsumed messages, Publish blocks beyond that
+func NewRecieverMemory[M any](f func([]byte) (M, error), buffer int, logger *slog.Logger) *ReceiverMemory[M] {
+	return &ReceiverMemory[M]{
+		messages: make(chan MemoryMessage, buffer),
+		decodeFn: f,
+		logger:   logger.With("component", "reciever_memory"),
+	}
+}
+
+// Publish queues value for Run and returns its offset
+func (r *ReceiverMemory[M]) Publish(ctx context.Context, key string, value []byte) (int64, error) {
+	r.mu.Lock()
+	msg := MemoryMessage{Key: key, Value: value, Offset: r.offset}
+	r.offset++
+	r.mu.Unlock()
+
+	select {
+	case r.messages <- msg:
+		return msg.Offset, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Run consumes messages until ctx is cancelled, a message being processed is finished first.
+// A message handle aborts is neither counted nor dead-lettered, Run returns the error.
+func (r *ReceiverMemory[M]) Run(ctx context.Context, handle func(context.Context, M) error) error {
+	//like ReceiverKafka, handle bounds the processing with the caller's deadline
+	procCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-r.messages:
+			id := msg.CorrelationID
+			if id == "" {
+				id = fmt.Sprintf("memory-%d", msg.Offset)
+			}
+			if err := r.process(logging.WithCorrelationID(procCtx, id), msg, handle); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// process returns an error only for an aborted message, like ReceiverKafka.process
+func (r *ReceiverMemory[M]) process(ctx context.Context, msg MemoryMessage, handle func(context.Context, M) error) error {
+	m, err := r.decodeFn(msg.Value)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to decode message", "offset", msg.Offset, "error", err)
+		metrics.KafkaMessage(metrics.KafkaDecodeFailed)
+		r.deadLetter(msg, metrics.KafkaDecodeFailed, err)
+		return nil
+	}
+	err = handle(ctx, m)
+	if errors.Is(err, ports.ErrAborted) {
+		r.logger.WarnContext(ctx, "order aborted", "offset", msg.Offset, "error", err)
+		return err
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to process the order", "offset", msg.Offset, "error", err)
+		metrics.KafkaMessage(metrics.KafkaProcessFailed)
+		r.deadLetter(msg, metrics.KafkaProcessFailed, err)
+		return nil
+	}
+	metrics.KafkaMessage(metrics.KafkaProcessed)
+	r.mu.Lock()
+	r.processed++
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *ReceiverMemory[M]) deadLetter(msg MemoryMessage, reason string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dead = append(r.dead, DeadLetter{Message: msg, Reason: reason, Err: err})
+}
+
+// Processed is the number of messages handled successfully
+func (r *ReceiverMemory[M]) Processed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.processed
+}
+
+// DeadLetters returns the messages that failed so far, oldest first
+func (r *ReceiverMemory[M]) DeadLetters() []DeadLetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]DeadLetter(nil), r.dead...)
+}
@@ -0,0 +1,36 @@
+package reciever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order_service/internal/logging"
+	"order_service/internal/ports"
+	"testing"
+	"time"
+)
+
+func TestMemoryAbortedMessageIsNotDeadLettered(t *testing.T) {
+	r := NewRecieverMemory(decodeRecord, 1, logging.Nop())
+	if _, err := r.Publish(context.Background(), "a", []byte(`{"id": "a"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(context.Background(), func(ctx context.Context, rec record) error {
+			return fmt.Errorf("%w: %w", ports.ErrAborted, context.Canceled)
+		})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ports.ErrAborted) {
+			t.Fatalf("got %v, want ports.ErrAborted", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run went on after the message was aborted")
+	}
+	if dead := r.DeadLetters(); len(dead) != 0 || r.Processed() != 0 {
+		t.Fatalf("dead letters %+v, processed %d: want none", dead, r.Processed())
+	}
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"order_service/internal/errdef"
+	"order_service/internal/models"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operation names passed to a Fault
+const (
+	OpSave    = "save"
+	OpGet     = "get"
+	OpGetMany = "get_many"
+	OpList    = "list"
+	OpStream  = "stream"
+)
+
+// Fault runs before every storage operation, a non-nil error fails the operation with it.
+// It may also sleep to simulate a slow database.
+type Fault func(ctx context.Context, op string) error
+
+// FailOn returns a Fault failing the given operations (all of them when none are given) with err
+func FailOn(err error, ops ...string) Fault {
+	return func(_ context.Context, op string) error {
+		if len(ops) == 0 || slices.Contains(ops, op) {
+			return err
+		}
+		return nil
+	}
+}
+
+// OrderStorageMemory keeps orders in a map, for tests and the dependency-free dev mode.
+// It follows the postgres storage: duplicate orders and payment transactions are rejected,
+// lists and streams are ordered by order_uid. Orders are copied in and out.
+type OrderStorageMemory struct {
+	mu           sync.RWMutex
+	orders       map[string]models.Order
+	transactions map[string]string
+	fault        Fault
+}
+
+func NewOrderStorageMemory() *OrderStorageMemory {
+	return &OrderStorageMemory{orders: map[string]models.Order{}, transactions: map[string]string{}}
+}
+
+// SetFault installs f for the following operations, nil removes it
+func (s *OrderStorageMemory) SetFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fault = f
+}
+
+func (s *OrderStorageMemory) inject(ctx context.Context, op string) error {
+	s.mu.RLock()
+	f := s.fault
+	s.mu.RUnlock()
+	if f == nil {
+		return ctx.Err()
+	}
+	if err := f(ctx, op); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Len is the number of stored orders
+func (s *OrderStorageMemory) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.orders)
+}
+
+func (s *OrderStorageMemory) SaveOrder(ctx context.Context, order models.Order) error {
+	if err := s.inject(ctx, OpSave); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.orders[order.OrderUID]; ok {
+		return ErrAlreadyExists
+	}
+	if _, ok := s.transactions[order.Payment.Transaction]; ok {
+		return fmt.Errorf("payment transaction %w", errdef.ErrAlreadyExists)
+	}
+	stored := clone(order)
+	//postgres keeps microseconds, so does this storage
+	stored.DateCreated = order.DateCreated.Truncate(time.Microsecond)
+	s.orders[order.OrderUID] = stored
+	s.transactions[order.Payment.Transaction] = order.OrderUID
+	return nil
+}
+
+func (s *OrderStorageMemory) GetOrderByID(ctx context.Context, id string) (models.Order, error) {
+	if err := s.inject(ctx, OpGet); err != nil {
+		return models.Order{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.orders[id]
+	if !ok {
+		return models.Order{}, ErrNotFound
+	}
+	return clone(o), nil
+}
+
+// GetOrdersByIDs returns the orders that exist, in no particular order
+func (s *OrderStorageMemory) GetOrdersByIDs(ctx context.Context, ids []string) ([]models.Order, error) {
+	if err := s.inject(ctx, OpGetMany); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var orders []models.Order
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if o, ok := s.orders[id]; ok && !seen[id] {
+			seen[id] = true
+			orders = append(orders, clone(o))
+		}
+	}
+	return orders, nil
+}
+
+// ListOrders returns up to filter.Limit orders matching filter ordered by order_uid
+func (s *OrderStorageMemory) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, error) {
+	if err := s.inject(ctx, OpList); err != nil {
+		return nil, err
+	}
+	orders := s.matching(filter)
+	if filter.Limit > 0 && len(orders) > filter.Limit {
+		orders = orders[:filter.Limit]
+	}
+	return orders, nil
+}
+
+// StreamOrders calls fn with a snapshot of the matching orders, the lock is not held while fn runs
+func (s *OrderStorageMemory) StreamOrders(ctx context.Context, filter models.OrderFilter, fn func(models.Order) error) error {
+	if err := s.inject(ctx, OpStream); err != nil {
+		return err
+	}
+	orders := s.matching(filter)
+	if filter.Limit > 0 && len(orders) > filter.Limit {
+		orders = orders[:filter.Limit]
+	}
+	for _, o := range orders {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matching applies filter like filterClause does in SQL, the result is ordered by order_uid
+func (s *OrderStorageMemory) matching(filter models.OrderFilter) []models.Order {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var orders []models.Order
+	for _, o := range s.orders {
+		switch {
+		case filter.CustomerID != "" && o.CustomerID != filter.CustomerID:
+		case filter.DeliveryService != "" && o.DeliveryService != filter.DeliveryService:
+		case filter.MinAmount > 0 && o.Payment.Amount < filter.MinAmount:
+		case !filter.CreatedFrom.IsZero() && o.DateCreated.Before(filter.CreatedFrom):
+		case !filter.CreatedTo.IsZero() && !o.DateCreated.Before(filter.CreatedTo):
+		case filter.AfterUID != "" && o.OrderUID <= filter.AfterUID:
+		default:
+			orders = append(orders, clone(o))
+		}
+	}
+	slices.SortFunc(orders, func(a, b models.Order) int {
+		return strings.Compare(a.OrderUID, b.OrderUID)
+	})
+	return orders
+}
+
+// clone copies the items so callers can't change stored orders through the shared slice.
+// Items come back ordered by chrt_id, then insertion order, like the items query returns them.
+func clone(o models.Order) models.Order {
+	o.Items = slices.Clone(o.Items)
+	slices.SortStableFunc(o.Items, func(a, b models.Item) int {
+		return cmp.Compare(a.ChrtID, b.ChrtID)
+	})
+	return o
+}
@@ -0,0 +1,14 @@
+package storage_test
+
+import (
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/storage"
+	"order_service/internal/ports/storagetest"
+	"testing"
+)
+
+func TestOrderStorageMemory(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) ports.OrderStorage {
+		return storage.NewOrderStorageMemory()
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"order_service/internal/errdef"
+	"order_service/internal/metrics"
 	"order_service/internal/models"
 
 	"github.com/jackc/pgx/v5"
@@ -12,7 +15,8 @@ import (
 )
 
 type OrderStoragePostgres struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	logger *slog.Logger
 }
 
 // Queryer is a interface to work with a DB connection, enabling us to use *pool or TX with the same code
@@ -23,20 +27,27 @@ type Queryer interface {
 	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
 }
 
-func NewOrderStoragePostgres(pool *pgxpool.Pool) *OrderStoragePostgres {
-	return &OrderStoragePostgres{pool: pool}
+func NewOrderStoragePostgres(pool *pgxpool.Pool, logger *slog.Logger) *OrderStoragePostgres {
+	return &OrderStoragePostgres{pool: pool, logger: logger.With("component", "storage_postgres")}
 }
 
-func (s *OrderStoragePostgres) SaveOrder(ctx context.Context, order models.Order) error {
+func (s *OrderStoragePostgres) SaveOrder(ctx context.Context, order models.Order) (err error) {
+	done := metrics.StartQuery("save_order")
+	defer func() { done(err) }()
+
 	//create a tx, so if one part fails ,everything should fail
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
-	defer tx.Rollback(ctx)
-
 	if err != nil {
 		return fmt.Errorf("failed to BeginTX: %w", err)
 	}
+	defer tx.Rollback(ctx)
+
 	err = saveOrder(ctx, order, tx)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrAlreadyExists
+		}
 		return fmt.Errorf("failed to save Order element: %w", err)
 	}
 
@@ -52,21 +63,32 @@ func (s *OrderStoragePostgres) SaveOrder(ctx context.Context, order models.Order
 
 	err = savePayment(ctx, tx, order.Payment, order.OrderUID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			//payments.transaction is unique across orders
+			return fmt.Errorf("payment transaction %w", errdef.ErrAlreadyExists)
+		}
 		return fmt.Errorf("failed to save payment element: %w", err)
 	}
 
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to save commit TX:%w", err)
 	}
+	s.logger.DebugContext(ctx, "order committed", "order_uid", order.OrderUID, "items", len(order.Items))
 
 	return nil
 }
 
-var ErrNotFound = errors.New("order not found")
+var (
+	ErrNotFound      = fmt.Errorf("order %w", errdef.ErrNotFound)
+	ErrAlreadyExists = fmt.Errorf("order %w", errdef.ErrAlreadyExists)
+)
 
-func (s *OrderStoragePostgres) GetOrderByID(ctx context.Context, id string) (models.Order, error) {
-	// 1) Fetch header (single row)
-	const headerSQL = `
+// uniqueViolation is the postgres SQLSTATE for a duplicate key
+const uniqueViolation = "23505"
+
+// orderHeaderSQL selects everything but the items, callers append the WHERE clause
+const orderHeaderSQL = `
         SELECT 
             o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, 
             o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
@@ -76,58 +98,97 @@ func (s *OrderStoragePostgres) GetOrderByID(ctx context.Context, id string) (mod
         FROM orders o
         LEFT JOIN deliveries d ON o.order_uid = d.order_uid
         LEFT JOIN payments   p ON o.order_uid = p.order_uid
-        WHERE o.order_uid = $1
-    `
+`
+
+const orderItemsSQL = `
+        SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+        FROM items
+        WHERE order_uid = ANY($1)
+        ORDER BY order_uid, chrt_id, id
+`
+
+func scanOrderHeader(row pgx.Row) (models.Order, error) {
 	var (
 		o models.Order
 		d models.Delivery
 		p models.Payment
 	)
 	// Scan, handling NULLs: if any LEFT JOIN columns can be NULL, use sql.NullString/NullInt64 or COALESCE(...) in SQL.
-	err := s.pool.QueryRow(ctx, headerSQL, id).Scan(
+	err := row.Scan(
 		&o.OrderUID, &o.TrackNumber, &o.Entry, &o.Locale, &o.InternalSignature,
 		&o.CustomerID, &o.DeliveryService, &o.ShardKey, &o.SmID, &o.DateCreated, &o.OofShard,
 		&d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email,
 		&p.Transaction, &p.RequestID, &p.Currency, &p.Provider, &p.Amount, &p.PaymentDT, &p.Bank,
 		&p.DeliveryCost, &p.GoodsTotal, &p.CustomFee,
 	)
+	o.Delivery = d
+	o.Payment = p
+	return o, err
+}
+
+func (s *OrderStoragePostgres) GetOrderByID(ctx context.Context, id string) (models.Order, error) {
+	// 1) Fetch header (single row)
+	done := metrics.StartQuery("get_order_header")
+	o, err := scanOrderHeader(s.pool.QueryRow(ctx, orderHeaderSQL+" WHERE o.order_uid = $1", id))
+	//not found is a valid answer, not a failed query
+	if errors.Is(err, pgx.ErrNoRows) {
+		done(nil)
+	} else {
+		done(err)
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return models.Order{}, ErrNotFound
 		}
 		return models.Order{}, fmt.Errorf("get order header: %w", err)
 	}
-	o.Delivery = d
-	o.Payment = p
 
 	// 2) Fetch items (0..n rows)
-	const itemsSQL = `
-        SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-        FROM items
-        WHERE order_uid = $1
-        ORDER BY chrt_id
-    `
-	rows, err := s.pool.Query(ctx, itemsSQL, id)
+	items, err := s.loadItems(ctx, []string{id})
 	if err != nil {
-		return models.Order{}, fmt.Errorf("get order items: %w", err)
+		return models.Order{}, err
+	}
+	o.Items = items[id]
+	s.logger.DebugContext(ctx, "order loaded", "order_uid", id, "items", len(o.Items))
+
+	return o, nil
+}
+
+// loadItems fetches the items of all the given orders with a single query
+func (s *OrderStoragePostgres) loadItems(ctx context.Context, ids []string) (map[string][]models.Item, error) {
+	return loadItems(ctx, s.pool, ids)
+}
+
+func loadItems(ctx context.Context, q Queryer, ids []string) (map[string][]models.Item, error) {
+	done := metrics.StartQuery("get_order_items")
+	rows, err := q.Query(ctx, orderItemsSQL, ids)
+	if err != nil {
+		done(err)
+		return nil, fmt.Errorf("get order items: %w", err)
 	}
 	defer rows.Close()
 
+	items := make(map[string][]models.Item, len(ids))
 	for rows.Next() {
-		var it models.Item
+		var (
+			orderUID string
+			it       models.Item
+		)
 		if err := rows.Scan(
-			&it.ChrtID, &it.TrackNumber, &it.Price, &it.Rid, &it.Name, &it.Sale, &it.Size,
+			&orderUID, &it.ChrtID, &it.TrackNumber, &it.Price, &it.Rid, &it.Name, &it.Sale, &it.Size,
 			&it.TotalPrice, &it.NmID, &it.Brand, &it.Status,
 		); err != nil {
-			return models.Order{}, fmt.Errorf("scan item: %w", err)
+			done(err)
+			return nil, fmt.Errorf("scan item: %w", err)
 		}
-		o.Items = append(o.Items, it)
+		items[orderUID] = append(items[orderUID], it)
 	}
 	if err := rows.Err(); err != nil {
-		return models.Order{}, fmt.Errorf("items rows: %w", err)
+		done(err)
+		return nil, fmt.Errorf("items rows: %w", err)
 	}
-
-	return o, nil
+	done(nil)
+	return items, nil
 }
 
 // func (s *OrderStoragePostgres) GetLastOrders(ctx context.Context, limit int) ([]models.Order, error) {
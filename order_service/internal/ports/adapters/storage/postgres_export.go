@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"order_service/internal/metrics"
+	"order_service/internal/models"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// exportFetchSize is how many orders one FETCH returns, memory holds one batch at a time
+const exportFetchSize = 500
+
+// StreamOrders calls fn with every order matching filter, ordered by order_uid. The orders
+// come from a server-side cursor in a read-only repeatable read transaction, so the export
+// is a consistent snapshot whatever its size. filter.Limit 0 means all of them.
+func (s *OrderStoragePostgres) StreamOrders(ctx context.Context, filter models.OrderFilter, fn func(models.Order) error) (err error) {
+	done := metrics.StartQuery("stream_orders")
+	defer func() { done(err) }()
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to BeginTX: %w", err)
+	}
+	//read only, rolling back just ends it
+	defer tx.Rollback(ctx)
+
+	where, args := filterClause(filter)
+	sql := orderHeaderSQL + where + " ORDER BY o.order_uid"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		sql += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if _, err = tx.Exec(ctx, "DECLARE export_orders NO SCROLL CURSOR FOR "+sql, args...); err != nil {
+		return fmt.Errorf("declare export cursor: %w", err)
+	}
+
+	for {
+		batch, err := fetchOrders(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(batch))
+		for i, o := range batch {
+			ids[i] = o.OrderUID
+		}
+		items, err := loadItems(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+		for _, o := range batch {
+			o.Items = items[o.OrderUID]
+			if err := fn(o); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func fetchOrders(ctx context.Context, tx pgx.Tx) ([]models.Order, error) {
+	rows, err := tx.Query(ctx, "FETCH "+strconv.Itoa(exportFetchSize)+" FROM export_orders")
+	if err != nil {
+		return nil, fmt.Errorf("fetch export cursor: %w", err)
+	}
+	defer rows.Close()
+
+	batch := make([]models.Order, 0, exportFetchSize)
+	for rows.Next() {
+		o, err := scanOrderHeader(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan order header: %w", err)
+		}
+		batch = append(batch, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("export cursor rows: %w", err)
+	}
+	return batch, nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"order_service/internal/metrics"
+	"order_service/internal/models"
+	"strconv"
+	"strings"
+)
+
+// GetOrdersByIDs loads all the given orders with one header query and one items query
+func (s *OrderStoragePostgres) GetOrdersByIDs(ctx context.Context, ids []string) ([]models.Order, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.queryOrders(ctx, "get_orders_by_ids", orderHeaderSQL+" WHERE o.order_uid = ANY($1)", ids)
+}
+
+// ListOrders returns a page of orders matching filter ordered by order_uid
+func (s *OrderStoragePostgres) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, error) {
+	where, args := filterClause(filter)
+	args = append(args, filter.Limit)
+	sql := orderHeaderSQL + where + " ORDER BY o.order_uid LIMIT $" + strconv.Itoa(len(args))
+
+	return s.queryOrders(ctx, "list_orders", sql, args...)
+}
+
+// queryOrders runs a header query built on orderHeaderSQL and attaches the items of every row
+func (s *OrderStoragePostgres) queryOrders(ctx context.Context, name, sql string, args ...any) ([]models.Order, error) {
+	done := metrics.StartQuery(name)
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		done(err)
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	defer rows.Close()
+
+	var (
+		orders []models.Order
+		ids    []string
+	)
+	for rows.Next() {
+		o, err := scanOrderHeader(rows)
+		if err != nil {
+			done(err)
+			return nil, fmt.Errorf("scan order header: %w", err)
+		}
+		orders = append(orders, o)
+		ids = append(ids, o.OrderUID)
+	}
+	if err := rows.Err(); err != nil {
+		done(err)
+		return nil, fmt.Errorf("order header rows: %w", err)
+	}
+	done(nil)
+
+	if len(orders) == 0 {
+		return orders, nil
+	}
+	items, err := s.loadItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Items = items[orders[i].OrderUID]
+	}
+	return orders, nil
+}
+
+// filterClause renders the WHERE clause for filter, values are always passed as arguments
+func filterClause(filter models.OrderFilter) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.CustomerID != "" {
+		add("o.customer_id = $%d", filter.CustomerID)
+	}
+	if filter.DeliveryService != "" {
+		add("o.delivery_service = $%d", filter.DeliveryService)
+	}
+	if filter.MinAmount > 0 {
+		add("p.amount >= $%d", filter.MinAmount)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("o.date_created >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("o.date_created < $%d", filter.CreatedTo)
+	}
+	if filter.AfterUID != "" {
+		add("o.order_uid > $%d", filter.AfterUID)
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
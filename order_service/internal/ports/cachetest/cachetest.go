@@ -0,0 +1,193 @@
+// Package cachetest is the contract every ports.OrderCache adapter must satisfy.
+// The suite uses fresh order ids in every subtest, so a shared redis can be reused.
+package cachetest
+
+import (
+	"context"
+	"order_service/internal/generator"
+	"order_service/internal/models"
+	"order_service/internal/ports"
+	"order_service/internal/ports/storagetest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Factory returns the cache under test, called once per subtest
+type Factory func(t *testing.T) ports.OrderCache
+
+// Run runs the whole suite against the cache returned by newCache
+func Run(t *testing.T, newCache Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, c ports.OrderCache, gen *generator.Generator)
+	}{
+		{"RoundTrip", testRoundTrip},
+		{"Miss", testMiss},
+		{"Overwrite", testOverwrite},
+		{"GetMany", testGetMany},
+		{"CallerCannotChangeEntries", testIsolation},
+		{"Concurrent", testConcurrent},
+		{"DateCreatedPrecision", testDateCreatedPrecision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := generator.New(generator.Options{Seed: uint64(time.Now().UnixNano()), MinItems: 1, MaxItems: 4, Start: time.Now()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.fn(t, newCache(t), gen)
+		})
+	}
+}
+
+func set(t *testing.T, c ports.OrderCache, o models.Order) {
+	t.Helper()
+	if err := c.Set(context.Background(), o.OrderUID, o); err != nil {
+		t.Fatalf("set %s: %v", o.OrderUID, err)
+	}
+}
+
+func testRoundTrip(t *testing.T, c ports.OrderCache, gen *generator.Generator) {
+	want := gen.Order()
+	set(t, c, want)
+
+	got, ok, err := c.Get(context.Background(), want.OrderUID)
+	if err != nil || !ok {
+		t.Fatalf("get: ok %v, err %v", ok, err)
+	}
+	storagetest.AssertOrder(t, got, want)
+}
+
+func testMiss(t *testing.T, c ports.OrderCache, gen *generator.Generator) {
+	got, ok, err := c.Get(context.Background(), gen.Order().OrderUID)
+	if err != nil {
+		t.Fatalf("a miss is not an error: %v", err)
+	}
+	if ok || !reflect.DeepEqual(got, models.Order{}) {
+		t.Fatalf("a miss returned ok %v and %+v", ok, got)
+	}
+}
+
+func testOverwrite(t *testing.T, c ports.OrderCache, gen *generator.Generator) {
+	o := gen.Order()
+	set(t, c, o)
+	o.Locale = "changed"
+	o.Items = o.Items[:1]
+	set(t, c, o)
+
+	got, ok, err := c.Get(context.Background(), o.OrderUID)
+	if err != nil || !ok {
+		t.Fatalf("get: ok %v, err %v", ok, err)
+	}
+	storagetest.AssertOrder(t, got, o)
+}
+
+func testGetMany(t *testing.T, c ports.OrderCache, gen *generator.Generator) {
+	a, b := gen.Order(), gen.Order()
+	set(t, c, a)
+	set(t, c, b)
+	unknown := gen.Order().OrderUID
+
+	found, err := c.GetMany(context.Background(), []string{b.OrderUID, unknown, a.OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %d orders, want 2", len(found))
+	}
+	if _, ok := found[unknown]; ok {
+		t.Fatal("a miss is present in the result")
+	}
+	storagetest.AssertOrder(t, found[a.OrderUID], a)
+	storagetest.AssertOrder(t, found[b.OrderUID], b)
+
+	found, err = c.GetMany(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || len(found) != 0 {
+		t.Fatalf("no ids returned %v, want an empty map", found)
+	}
+}
+
+func testIsolation(t *testing.T, c ports.OrderCache, gen *generator.Generator) {
+	o := gen.Order()
+	want := o
+	want.Items = append([]models.Item(nil), o.Items...)
+	set(t, c, o)
+	o.Items[0].Name = "changed after set"
+
+	got, _, err := c.Get(context.Background(), o.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storagetest.AssertOrder(t, got, want)
+
+	got.Items[0].Name = "changed after get"
+	again, _, err := c.Get(context.Background(), o.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storagetest.AssertOrder(t, again, want)
+}
+
+func testConcurrent(t *testing.T, c ports.OrderCache, gen *generator.Generator) {
+	const workers = 8
+	orders := make([]models.Order, workers)
+	for i := range orders {
+		orders[i] = gen.Order()
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*2)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := range 20 {
+				o := orders[(i+round)%workers]
+				if err := c.Set(context.Background(), o.OrderUID, o); err != nil {
+					errs <- err
+					return
+				}
+				if _, _, err := c.Get(context.Background(), orders[(i+round+1)%workers].OrderUID); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	for _, o := range orders {
+		got, ok, err := c.Get(context.Background(), o.OrderUID)
+		if err != nil || !ok {
+			t.Fatalf("get %s: ok %v, err %v", o.OrderUID, ok, err)
+		}
+		storagetest.AssertOrder(t, got, o)
+	}
+}
+
+func testDateCreatedPrecision(t *testing.T, c ports.OrderCache, gen *generator.Generator) {
+	o := gen.Order()
+	//the cache serves what the storage returned, nothing may be lost on the way
+	o.DateCreated = time.Date(2026, 2, 3, 4, 5, 6, 123456789, time.FixedZone("UTC+9:30", 9*3600+1800))
+	set(t, c, o)
+
+	got, ok, err := c.Get(context.Background(), o.OrderUID)
+	if err != nil || !ok {
+		t.Fatalf("get: ok %v, err %v", ok, err)
+	}
+	if !got.DateCreated.Equal(o.DateCreated) {
+		t.Fatalf("date_created: got %s, want %s", got.DateCreated.Format(time.RFC3339Nano), o.DateCreated.Format(time.RFC3339Nano))
+	}
+	if _, offset := got.DateCreated.Zone(); offset != 9*3600+1800 {
+		t.Fatalf("date_created lost its offset: %s", got.DateCreated.Format(time.RFC3339Nano))
+	}
+}
@@ -2,26 +2,53 @@ package ports
 
 import (
 	"context"
+	"errors"
 	"order_service/internal/models"
 )
 
 type OrderStorage interface {
 	GetOrderByID(ctx context.Context, id string) (models.Order, error)
+	// GetOrdersByIDs returns the orders that exist, in no particular order
+	GetOrdersByIDs(ctx context.Context, ids []string) ([]models.Order, error)
 	// GetLastOrders(ctx context.Context, limit int) ([]models.Order, error)
 	SaveOrder(ctx context.Context, order models.Order) error
+	// ListOrders returns up to filter.Limit orders ordered by order_uid
+	ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, error)
+	// StreamOrders calls fn with every order matching filter ordered by order_uid, without
+	// holding them all in memory. An error from fn stops the stream and is returned.
+	StreamOrders(ctx context.Context, filter models.OrderFilter, fn func(models.Order) error) error
 }
 
 type OrderCache interface {
 	Set(ctx context.Context, id string, order models.Order) error
 	Get(ctx context.Context, id string) (models.Order, bool, error)
+	// GetMany returns the cached orders by id, misses are simply absent from the map
+	GetMany(ctx context.Context, ids []string) (map[string]models.Order, error)
 	// GetKeys() []string
 	// GetKeysAmount() int
 }
 
-type OrderReciever[MessageType any] interface {
-	Consume(ctx context.Context) (models.Order, MessageType, error)
+// APIKeyStore keeps hashed API keys, lookups go by hash so the plain key is never stored
+type APIKeyStore interface {
+	GetByHash(ctx context.Context, hash string) (models.APIKey, error)
+	Create(ctx context.Context, key models.APIKey) error
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+// OrderNotifier is told about every order that was saved successfully, it must not block
+type OrderNotifier interface {
+	OrderSaved(ctx context.Context, order models.Order)
+}
 
-	OnSuccess(ctx context.Context, msg MessageType) error
+// ErrAborted is wrapped by handle errors when the order was cancelled at the shutdown deadline
+// before it was saved. It is not a failure of the message: recievers leave it for redelivery
+// instead of dead-lettering, rejecting or committing it.
+var ErrAborted = errors.New("processing aborted at the shutdown deadline")
 
-	OnFail(ctx context.Context, shouldRetry bool, msg MessageType) error
+// OrderReciever takes orders from a message source and passes each one to handle until ctx
+// is cancelled. Messages handle fails on are the reciever's to deal with (DLQ, rejects file),
+// an error is only returned when the source itself fails or a message was aborted, see ErrAborted.
+type OrderReciever interface {
+	Run(ctx context.Context, handle func(context.Context, models.Order) error) error
 }
@@ -0,0 +1,470 @@
+// Package storagetest is the contract every ports.OrderStorage adapter must satisfy.
+// An adapter's tests call Run with a factory, the suite never assumes the storage is
+// empty: every subtest works with its own orders and customer id, so a shared database
+// can be reused between runs.
+package storagetest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"order_service/internal/errdef"
+	"order_service/internal/generator"
+	"order_service/internal/models"
+	"order_service/internal/ports"
+	"order_service/internal/ports/adapters/storage"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Factory returns the storage under test, called once per subtest
+type Factory func(t *testing.T) ports.OrderStorage
+
+// Run runs the whole suite against the storage returned by newStorage
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s ports.OrderStorage, g *orders)
+	}{
+		{"RoundTrip", testRoundTrip},
+		{"ItemsOrderedByChrtID", testItemsOrder},
+		{"NotFound", testNotFound},
+		{"DuplicateOrder", testDuplicateOrder},
+		{"DuplicateTransactionSavesNothing", testDuplicateTransaction},
+		{"GetOrdersByIDs", testGetOrdersByIDs},
+		{"ListFilters", testListFilters},
+		{"ListPages", testListPages},
+		{"Stream", testStream},
+		{"StreamStopsOnError", testStreamStopsOnError},
+		{"ConcurrentSaves", testConcurrentSaves},
+		{"DateCreatedPrecision", testDateCreatedPrecision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t), newOrders(t))
+		})
+	}
+}
+
+// orders makes valid orders that no other run has used, all with the same customer
+type orders struct {
+	gen      *generator.Generator
+	customer string
+}
+
+func newOrders(t *testing.T) *orders {
+	t.Helper()
+	seed := uint64(time.Now().UnixNano())
+	gen, err := generator.New(generator.Options{Seed: seed, MinItems: 1, MaxItems: 4, Start: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &orders{gen: gen, customer: fmt.Sprintf("storagetest-%d", seed)}
+}
+
+func (g *orders) next() models.Order {
+	o := g.gen.Order()
+	o.CustomerID = g.customer
+	return Stored(o)
+}
+
+// Stored is o the way a storage gives it back: items ordered by chrt_id, then in the order
+// they were saved, and DateCreated in microseconds, like postgres keeps it
+func Stored(o models.Order) models.Order {
+	o.Items = slices.Clone(o.Items)
+	slices.SortStableFunc(o.Items, func(a, b models.Item) int {
+		return cmp.Compare(a.ChrtID, b.ChrtID)
+	})
+	o.DateCreated = o.DateCreated.Truncate(time.Microsecond)
+	return o
+}
+
+func save(t *testing.T, s ports.OrderStorage, o models.Order) {
+	t.Helper()
+	if err := s.SaveOrder(context.Background(), o); err != nil {
+		t.Fatalf("save %s: %v", o.OrderUID, err)
+	}
+}
+
+// AssertOrder compares orders field by field, DateCreated is compared as an instant
+// and no items equals empty items
+func AssertOrder(t *testing.T, got, want models.Order) {
+	t.Helper()
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Errorf("date_created: got %s, want %s", got.DateCreated, want.DateCreated)
+	}
+	got.DateCreated, want.DateCreated = time.Time{}, time.Time{}
+	if len(got.Items) == 0 && len(want.Items) == 0 {
+		got.Items, want.Items = nil, nil
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order %s:\n got %+v\nwant %+v", want.OrderUID, got, want)
+	}
+}
+
+func uids(orders []models.Order) []string {
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.OrderUID
+	}
+	return ids
+}
+
+func sortedUIDs(orders []models.Order) []string {
+	ids := uids(orders)
+	slices.Sort(ids)
+	return ids
+}
+
+func testRoundTrip(t *testing.T, s ports.OrderStorage, g *orders) {
+	want := g.next()
+	want.InternalSignature = "signed"
+	want.Payment.RequestID = "request-1"
+	want.Payment.CustomFee = 7
+	save(t, s, want)
+
+	got, err := s.GetOrderByID(context.Background(), want.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertOrder(t, got, want)
+}
+
+func testItemsOrder(t *testing.T, s ports.OrderStorage, g *orders) {
+	want := g.next()
+	base := want.Items[0]
+	want.Items = nil
+	//saved out of order, read back by ascending chrt_id; the two items sharing chrt_id 100
+	//are told apart by their rid and keep the order they were saved in
+	for i, chrt := range []int{900, 100, 500, 100, 1} {
+		it := base
+		it.ChrtID = chrt
+		it.Rid = fmt.Sprintf("rid-%d", i)
+		want.Items = append(want.Items, it)
+	}
+	save(t, s, want)
+	saved := want.Items
+	want.Items = []models.Item{saved[4], saved[1], saved[3], saved[2], saved[0]}
+
+	got, err := s.GetOrderByID(context.Background(), want.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertOrder(t, got, want)
+
+	batch, err := s.GetOrdersByIDs(context.Background(), []string{want.OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 1 {
+		t.Fatalf("GetOrdersByIDs returned %d orders, want 1", len(batch))
+	}
+	AssertOrder(t, batch[0], want)
+}
+
+func testNotFound(t *testing.T, s ports.OrderStorage, g *orders) {
+	_, err := s.GetOrderByID(context.Background(), g.next().OrderUID)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got %v, want storage.ErrNotFound", err)
+	}
+	if !errors.Is(err, errdef.ErrNotFound) {
+		t.Fatalf("%v does not match errdef.ErrNotFound", err)
+	}
+
+	found, err := s.GetOrdersByIDs(context.Background(), []string{g.next().OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("GetOrdersByIDs found %v for an unknown id", uids(found))
+	}
+}
+
+func testDuplicateOrder(t *testing.T, s ports.OrderStorage, g *orders) {
+	first := g.next()
+	save(t, s, first)
+
+	again := first
+	again.Locale = "changed"
+	again.Payment.Transaction = g.next().Payment.Transaction
+	err := s.SaveOrder(context.Background(), again)
+	if !errors.Is(err, storage.ErrAlreadyExists) {
+		t.Fatalf("got %v, want storage.ErrAlreadyExists", err)
+	}
+
+	got, err := s.GetOrderByID(context.Background(), first.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertOrder(t, got, first)
+}
+
+func testDuplicateTransaction(t *testing.T, s ports.OrderStorage, g *orders) {
+	first := g.next()
+	save(t, s, first)
+
+	second := g.next()
+	second.Payment.Transaction = first.Payment.Transaction
+	err := s.SaveOrder(context.Background(), second)
+	if !errors.Is(err, errdef.ErrAlreadyExists) {
+		t.Fatalf("got %v, want errdef.ErrAlreadyExists", err)
+	}
+
+	//the order is saved as a whole or not at all
+	if _, err := s.GetOrderByID(context.Background(), second.OrderUID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("the rejected order is readable: %v", err)
+	}
+	second.Payment.Transaction = second.OrderUID + "-retry"
+	save(t, s, second)
+}
+
+func testGetOrdersByIDs(t *testing.T, s ports.OrderStorage, g *orders) {
+	a, b := g.next(), g.next()
+	save(t, s, a)
+	save(t, s, b)
+
+	found, err := s.GetOrdersByIDs(context.Background(), []string{b.OrderUID, g.next().OrderUID, a.OrderUID, b.OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{a.OrderUID, b.OrderUID}
+	slices.Sort(want)
+	if got := sortedUIDs(found); !slices.Equal(got, want) {
+		t.Fatalf("found %v, want %v", got, want)
+	}
+	for _, o := range found {
+		if o.OrderUID == a.OrderUID {
+			AssertOrder(t, o, a)
+		} else {
+			AssertOrder(t, o, b)
+		}
+	}
+
+	found, err = s.GetOrdersByIDs(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("no ids found %v", uids(found))
+	}
+}
+
+// saveMany saves n orders of the suite's customer and returns their sorted ids
+func saveMany(t *testing.T, s ports.OrderStorage, g *orders, n int, edit func(i int, o *models.Order)) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range n {
+		o := g.next()
+		if edit != nil {
+			edit(i, &o)
+		}
+		save(t, s, o)
+		ids[i] = o.OrderUID
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func testListFilters(t *testing.T, s ports.OrderStorage, g *orders) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	byIndex := map[int]string{}
+	saveMany(t, s, g, 6, func(i int, o *models.Order) {
+		o.DateCreated = start.Add(time.Duration(i) * time.Hour)
+		o.DeliveryService = []string{"cdek", "meest"}[i%2]
+		o.Payment.Amount = i * 100
+		byIndex[i] = o.OrderUID
+	})
+	pick := func(is ...int) []string {
+		var ids []string
+		for _, i := range is {
+			ids = append(ids, byIndex[i])
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		filter models.OrderFilter
+		want   []string
+	}{
+		{"customer", models.OrderFilter{}, pick(0, 1, 2, 3, 4, 5)},
+		{"delivery service", models.OrderFilter{DeliveryService: "meest"}, pick(1, 3, 5)},
+		{"min amount", models.OrderFilter{MinAmount: 300}, pick(3, 4, 5)},
+		{"created from is inclusive", models.OrderFilter{CreatedFrom: start.Add(4 * time.Hour)}, pick(4, 5)},
+		{"created to is exclusive", models.OrderFilter{CreatedTo: start.Add(2 * time.Hour)}, pick(0, 1)},
+		{"combined", models.OrderFilter{DeliveryService: "cdek", MinAmount: 100, CreatedTo: start.Add(5 * time.Hour)}, pick(2, 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.CustomerID = g.customer
+			tt.filter.Limit = 100
+			got, err := s.ListOrders(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(uids(got), tt.want) {
+				t.Fatalf("listed %v, want %v", uids(got), tt.want)
+			}
+		})
+	}
+}
+
+func testListPages(t *testing.T, s ports.OrderStorage, g *orders) {
+	want := saveMany(t, s, g, 7, nil)
+
+	var got []string
+	filter := models.OrderFilter{CustomerID: g.customer, Limit: 3}
+	for page := 0; ; page++ {
+		if page > len(want) {
+			t.Fatal("paging does not end")
+		}
+		orders, err := s.ListOrders(context.Background(), filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orders) > filter.Limit {
+			t.Fatalf("page of %d orders, limit is %d", len(orders), filter.Limit)
+		}
+		if len(orders) == 0 {
+			break
+		}
+		got = append(got, uids(orders)...)
+		filter.AfterUID = orders[len(orders)-1].OrderUID
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("paged through %v, want %v", got, want)
+	}
+}
+
+func testStream(t *testing.T, s ports.OrderStorage, g *orders) {
+	want := saveMany(t, s, g, 5, nil)
+
+	var got []models.Order
+	err := s.StreamOrders(context.Background(), models.OrderFilter{CustomerID: g.customer}, func(o models.Order) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(uids(got), want) {
+		t.Fatalf("streamed %v, want %v", uids(got), want)
+	}
+	for _, o := range got {
+		if len(o.Items) == 0 {
+			t.Fatalf("order %s was streamed without its items", o.OrderUID)
+		}
+	}
+
+	got = nil
+	err = s.StreamOrders(context.Background(), models.OrderFilter{CustomerID: g.customer, Limit: 2}, func(o models.Order) error {
+		got = append(got, o)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(uids(got), want[:2]) {
+		t.Fatalf("streamed %v with limit 2, want %v", uids(got), want[:2])
+	}
+}
+
+func testStreamStopsOnError(t *testing.T, s ports.OrderStorage, g *orders) {
+	saveMany(t, s, g, 3, nil)
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.StreamOrders(context.Background(), models.OrderFilter{CustomerID: g.customer}, func(models.Order) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("got %v, want the callback's error", err)
+	}
+	if calls != 1 {
+		t.Fatalf("callback ran %d times after failing, want 1", calls)
+	}
+}
+
+func testConcurrentSaves(t *testing.T, s ports.OrderStorage, g *orders) {
+	const workers = 8
+	contested := g.next()
+	distinct := make([]models.Order, workers)
+	for i := range distinct {
+		distinct[i] = g.next()
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		won       int
+		otherErrs []error
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.SaveOrder(context.Background(), distinct[i]); err != nil {
+				mu.Lock()
+				otherErrs = append(otherErrs, err)
+				mu.Unlock()
+			}
+			err := s.SaveOrder(context.Background(), contested)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, errdef.ErrAlreadyExists):
+				otherErrs = append(otherErrs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(otherErrs) > 0 {
+		t.Fatalf("concurrent saves failed: %v", errors.Join(otherErrs...))
+	}
+	if won != 1 {
+		t.Fatalf("the same order was saved %d times, want exactly once", won)
+	}
+	for _, o := range append(distinct, contested) {
+		got, err := s.GetOrderByID(context.Background(), o.OrderUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertOrder(t, got, o)
+	}
+}
+
+func testDateCreatedPrecision(t *testing.T, s ports.OrderStorage, g *orders) {
+	want := g.next()
+	//microseconds in a zone far from UTC, the instant must survive the round trip
+	want.DateCreated = time.Date(2026, 2, 3, 4, 5, 6, 123456000, time.FixedZone("UTC+9:30", 9*3600+1800))
+	save(t, s, want)
+
+	got, err := s.GetOrderByID(context.Background(), want.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Fatalf("date_created: got %s, want %s", got.DateCreated.Format(time.RFC3339Nano), want.DateCreated.Format(time.RFC3339Nano))
+	}
+
+	//nanoseconds are not kept, but never rounded into the next microsecond either
+	ns := g.next()
+	ns.DateCreated = want.DateCreated.Add(999 * time.Nanosecond)
+	save(t, s, ns)
+	got, err = s.GetOrderByID(context.Background(), ns.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Fatalf("date_created with nanoseconds: got %s, want %s", got.DateCreated.Format(time.RFC3339Nano), want.DateCreated.Format(time.RFC3339Nano))
+	}
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"order_service/internal/auth"
+	"order_service/internal/errdef"
+	"order_service/internal/logging"
+	"order_service/internal/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// Middleware limits requests per client and route. A client is the API key or JWT
+// subject when the request is authenticated, the client IP otherwise.
+type Middleware struct {
+	limiter Limiter
+	routes  Routes
+	// ipLimit caps every client IP before authentication, zero disables it
+	ipLimit Limit
+	// trustProxy takes the client IP from X-Forwarded-For, only safe behind a proxy that sets it
+	trustProxy bool
+	logger     *slog.Logger
+}
+
+func NewMiddleware(limiter Limiter, routes Routes, trustProxy bool, logger *slog.Logger) *Middleware {
+	return &Middleware{limiter: limiter, routes: routes, trustProxy: trustProxy, logger: logger.With("component", "ratelimit")}
+}
+
+// WithIPLimit caps all requests of a client IP with limit before they are authenticated,
+// so bad credentials can't be tried, or looked up in the key store, at an unlimited rate
+func (m *Middleware) WithIPLimit(limit Limit) *Middleware {
+	m.ipLimit = limit
+	return m
+}
+
+// ByIP applies the IP limit of WithIPLimit, it runs in front of auth.Middleware.
+// A nil Middleware or one without an IP limit lets everything through.
+func (m *Middleware) ByIP(next http.Handler) http.Handler {
+	if m == nil || m.ipLimit.Rate <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := m.clientIP(r)
+		res, err := m.limiter.Allow(r.Context(), "ip:"+ip+"|*", m.ipLimit)
+		if err != nil {
+			m.logger.WarnContext(r.Context(), "rate limiter failed, letting the request through", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !res.Allowed {
+			m.reject(w, r, res, "*", "ip:"+ip, m.ipLimit)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Handler must run after routing (inline with chi's With) so the route pattern is known,
+// and after auth.Middleware so authenticated clients are limited by key.
+// A nil Middleware lets everything through.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			pattern = rctx.RoutePattern()
+		}
+		limit := m.routes.For(r.Method, pattern)
+		key := m.clientKey(r) + "|" + r.Method + " " + pattern
+
+		res, err := m.limiter.Allow(r.Context(), key, limit)
+		if err != nil {
+			//a broken limiter must not take the API down with it
+			m.logger.WarnContext(r.Context(), "rate limiter failed, letting the request through", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !res.Allowed {
+			m.reject(w, r, res, pattern, m.clientKey(r), limit)
+			return
+		}
+		setHeaders(w, res, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reject answers 429 for a request over limit
+func (m *Middleware) reject(w http.ResponseWriter, r *http.Request, res Result, pattern, client string, limit Limit) {
+	metrics.RateLimited(pattern)
+	setHeaders(w, res, limit)
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+	m.logger.InfoContext(r.Context(), "rate limited", "client", client, "route", pattern, "limit", limit.String())
+
+	problem := errdef.FromError(errdef.ErrRateLimited)
+	problem.Instance = r.URL.Path
+	problem.CorrelationID = logging.CorrelationID(r.Context())
+	problem.Write(w)
+}
+
+func setHeaders(w http.ResponseWriter, res Result, limit Limit) {
+	h := w.Header()
+	h.Set("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+strconv.Itoa(ceilSeconds(seconds(float64(limit.Burst)/limit.Rate))))
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+}
+
+func (m *Middleware) clientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok && p.Method != auth.Anonymous.Method {
+		return "key:" + p.Subject
+	}
+	return "ip:" + m.clientIP(r)
+}
+
+func (m *Middleware) clientIP(r *http.Request) string {
+	if m.trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket has refilled, past it the bucket is the same as a new one
+	full time.Time
+}
+
+// Memory keeps the buckets in this process, so every replica has its own limits
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (m *Memory) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(seconds((float64(limit.Burst) - b.tokens) / limit.Rate))
+	return result(limit, allowed, b.tokens), nil
+}
+
+// Cleanup drops the refilled buckets every interval until ctx is done,
+// dropping them changes nothing for their clients
+func (m *Memory) Cleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+			now := m.now()
+			for key, b := range m.buckets {
+				if now.After(b.full) {
+					delete(m.buckets, key)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
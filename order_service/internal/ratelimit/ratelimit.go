@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled at Rate per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%g/s burst %d", l.Rate, l.Burst)
+}
+
+// Result is the state of a bucket after one request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed, 0 when Allowed
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token from the bucket for key
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result turns the tokens left in a bucket into a Result
+func result(limit Limit, allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Routes holds the limit of every route, keyed by "METHOD pattern" as chi registers it
+type Routes struct {
+	Default Limit
+	routes  map[string]Limit
+}
+
+// ParseRoutes reads per-route overrides of def, written as
+// "GET /order/{id}=50:100;POST /orders:batchGet=5:10" (rate per second:burst)
+func ParseRoutes(def Limit, s string) (Routes, error) {
+	if def.Rate <= 0 || def.Burst < 1 {
+		return Routes{}, fmt.Errorf("rate limit: default rate and burst must be positive, got %s", def)
+	}
+	r := Routes{Default: def, routes: make(map[string]Limit)}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			return Routes{}, fmt.Errorf("rate limit: %q is not \"METHOD /route=rate:burst\"", entry)
+		}
+		route, spec := strings.Join(strings.Fields(entry[:i]), " "), entry[i+1:]
+		rate, burst, ok := strings.Cut(spec, ":")
+		if !ok {
+			return Routes{}, fmt.Errorf("rate limit: %q: want rate:burst", entry)
+		}
+		var l Limit
+		var err error
+		if l.Rate, err = strconv.ParseFloat(rate, 64); err != nil || l.Rate <= 0 {
+			return Routes{}, fmt.Errorf("rate limit: %q: rate must be a positive number", entry)
+		}
+		if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst < 1 {
+			return Routes{}, fmt.Errorf("rate limit: %q: burst must be a positive integer", entry)
+		}
+		r.routes[route] = l
+	}
+	return r, nil
+}
+
+// For returns the limit of a route, or the default one
+func (r Routes) For(method, pattern string) Limit {
+	if l, ok := r.routes[method+" "+pattern]; ok {
+		return l
+	}
+	return r.Default
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucket refills and takes one token atomically, using the redis clock so the
+// replicas' clocks don't matter. The bucket expires once it would be full again.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000000 * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis keeps the buckets in redis, so the limits hold across replicas
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client, prefix: "ratelimit:"}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	res, err := tokenBucket.Run(ctx, r.client, []string{r.prefix + key}, limit.Rate, limit.Burst).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	if len(res) != 2 {
+		return Result{}, fmt.Errorf("rate limit script: unexpected reply %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	s, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script: tokens %q: %w", s, err)
+	}
+	return result(limit, allowed == 1, tokens), nil
+}
@@ -2,22 +2,46 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"order_service/internal/models"
-	"order_service/internal/ports/adapters/reciever"
+	"order_service/internal/ports"
 )
 
 type OrderReciverService struct {
-	reciever         *reciever.ReceiverKafka[models.Order]
+	reciever         ports.OrderReciever
 	orderProcessFunc func(ctx context.Context, order models.Order) error
+	abort            context.Context
 }
 
-func NewOrderRecieverService(reciever *reciever.ReceiverKafka[models.Order], f func(ctx context.Context, order models.Order) error) *OrderReciverService {
+func NewOrderRecieverService(reciever ports.OrderReciever, f func(ctx context.Context, order models.Order) error) *OrderReciverService {
 	return &OrderReciverService{
 		reciever:         reciever,
 		orderProcessFunc: f,
 	}
 }
 
+// WithAbort cancels the orders being processed once abort is done. The recievers finish
+// the orders in flight after Run's ctx is cancelled, abort bounds how long that may take.
+// An order that fails after the cancellation returns an error wrapping ports.ErrAborted.
+func (o *OrderReciverService) WithAbort(abort context.Context) *OrderReciverService {
+	o.abort = abort
+	return o
+}
+
 func (o *OrderReciverService) Run(ctx context.Context) error {
-	return o.reciever.Run(ctx, o.orderProcessFunc)
+	if o.abort == nil {
+		return o.reciever.Run(ctx, o.orderProcessFunc)
+	}
+	return o.reciever.Run(ctx, func(ctx context.Context, order models.Order) error {
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		stop := context.AfterFunc(o.abort, func() { cancel(ports.ErrAborted) })
+		defer stop()
+		err := o.orderProcessFunc(ctx, order)
+		if err != nil && errors.Is(context.Cause(ctx), ports.ErrAborted) && !errors.Is(err, ports.ErrAborted) {
+			return fmt.Errorf("%w: %w", ports.ErrAborted, err)
+		}
+		return err
+	})
 }
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"order_service/internal/models"
+	"order_service/internal/ports"
+	"testing"
+	"time"
+)
+
+// recieverFunc hands one order to handle with a context detached from Run's, like the recievers do
+type recieverFunc func(ctx context.Context, handle func(context.Context, models.Order) error) error
+
+func (f recieverFunc) Run(ctx context.Context, handle func(context.Context, models.Order) error) error {
+	return f(ctx, handle)
+}
+
+func TestRecieverServiceAbortCancelsOrderInFlight(t *testing.T) {
+	started := make(chan struct{})
+	rec := recieverFunc(func(ctx context.Context, handle func(context.Context, models.Order) error) error {
+		return handle(context.WithoutCancel(ctx), models.Order{})
+	})
+	hung := func(ctx context.Context, _ models.Order) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	abort, cancelAbort := context.WithCancel(context.Background())
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewOrderRecieverService(rec, hung).WithAbort(abort).Run(ctx)
+	}()
+
+	<-started
+	//stopping only stops fetching, the order in flight goes on
+	stop()
+	select {
+	case err := <-done:
+		t.Fatalf("the order in flight was cancelled by the stop signal: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancelAbort()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ports.ErrAborted) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want ports.ErrAborted wrapping context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the order in flight was not cancelled by abort")
+	}
+}
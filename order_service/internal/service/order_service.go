@@ -2,38 +2,101 @@ package service
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"order_service/internal/errdef"
+	"order_service/internal/metrics"
 	"order_service/internal/models"
 	"order_service/internal/ports"
+	"order_service/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// MaxListLimit caps a single ListOrders page
+	MaxListLimit = 500
+	// DefaultMaxBatch caps GetOrders unless WithMaxBatch says otherwise
+	DefaultMaxBatch = 100
 )
 
 type OrderService struct {
-	storage ports.OrderStorage
-	cache   ports.OrderCache
+	storage   ports.OrderStorage
+	cache     ports.OrderCache
+	notifiers []ports.OrderNotifier
+	maxBatch  int
+	validate  bool
+	logger    *slog.Logger
 }
 
-func NewOrderService(storage ports.OrderStorage, cache ports.OrderCache) *OrderService {
-	return &OrderService{storage: storage, cache: cache}
+func NewOrderService(storage ports.OrderStorage, cache ports.OrderCache, logger *slog.Logger) *OrderService {
+	return &OrderService{storage: storage, cache: cache, maxBatch: DefaultMaxBatch, logger: logger.With("component", "order_service")}
 }
 
-func (s *OrderService) SaveOrder(ctx context.Context, order models.Order) error {
-	return s.storage.SaveOrder(ctx, order)
+// WithNotifier subscribes n to saved orders, whichever way they were ingested
+func (s *OrderService) WithNotifier(n ports.OrderNotifier) *OrderService {
+	s.notifiers = append(s.notifiers, n)
+	return s
 }
 
-func (s *OrderService) GetOrder(ctx context.Context, id string) (models.Order, error) {
+// WithMaxBatch sets how many ids a single GetOrders call may ask for
+func (s *OrderService) WithMaxBatch(n int) *OrderService {
+	if n > 0 {
+		s.maxBatch = n
+	}
+	return s
+}
+
+// WithValidation makes SaveOrder reject orders failing models.Order.Validate with an
+// *errdef.ValidationError, without it any decoded order is stored
+func (s *OrderService) WithValidation(enabled bool) *OrderService {
+	s.validate = enabled
+	return s
+}
 
-	var order models.Order
+func (s *OrderService) SaveOrder(ctx context.Context, order models.Order) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.SaveOrder", trace.WithAttributes(attribute.String("order_uid", order.OrderUID)))
+	defer func() { tracing.End(span, err) }()
+
+	if s.validate {
+		if err = order.Validate(); err != nil {
+			s.logger.WarnContext(ctx, "order rejected", "order_uid", order.OrderUID, "error", err)
+			return err
+		}
+	}
+
+	err = s.storage.SaveOrder(ctx, order)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "save order failed", "order_uid", order.OrderUID, "error", err)
+		return err
+	}
+	s.logger.InfoContext(ctx, "order saved", "order_uid", order.OrderUID, "items", len(order.Items))
+
+	for _, n := range s.notifiers {
+		n.OrderSaved(ctx, order)
+	}
+	return nil
+}
+
+func (s *OrderService) GetOrder(ctx context.Context, id string) (order models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetOrder", trace.WithAttributes(attribute.String("order_uid", id)))
+	defer func() { tracing.End(span, err) }()
 
 	//first try to get from the cache
 	order, ok, err := s.cache.Get(ctx, id)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
 	if ok {
-		log.Printf("[orderService][GetOrder] cache hit order_id=%s", id)
+		s.logger.DebugContext(ctx, "cache hit", "order_uid", id)
+		metrics.CacheLookup(metrics.CacheHit)
 		return order, nil
 	}
 	if err != nil {
-		log.Printf("[orderService][GetOrder] cache lookup failed order_id=%s: %v", id, err)
+		s.logger.WarnContext(ctx, "cache lookup failed", "order_uid", id, "error", err)
+		metrics.CacheLookup(metrics.CacheError)
 	} else {
-		log.Printf("[orderService][GetOrder] cache miss order_id=%s", id)
+		s.logger.DebugContext(ctx, "cache miss", "order_uid", id)
+		metrics.CacheLookup(metrics.CacheMiss)
 	}
 
 	//try to get from the storage
@@ -42,17 +105,123 @@ func (s *OrderService) GetOrder(ctx context.Context, id string) (models.Order, e
 		return order, err
 	}
 
-	//save to the cache for later use
+	//save to the cache for later use, detached from the request but keeping its correlation id
+	cacheCtx := context.WithoutCancel(ctx)
 	go func() {
-		err = s.cache.Set(context.TODO(), id, order)
+		err := s.cache.Set(cacheCtx, id, order)
 		if err != nil {
-			log.Printf("[orderService][GetOrder] cache set failed order_id=%s: %v", id, err)
+			s.logger.WarnContext(cacheCtx, "cache set failed", "order_uid", id, "error", err)
 		}
 	}()
 
 	return order, nil
 }
 
+// GetOrders looks up many orders at once: one MGET for the cached ones, then one storage
+// round trip for the misses. Found orders keep the order of ids, duplicates are collapsed.
+func (s *OrderService) GetOrders(ctx context.Context, ids []string) (found []models.Order, missing []string, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetOrders", trace.WithAttributes(attribute.Int("batch.size", len(ids))))
+	defer func() { tracing.End(span, err) }()
+
+	ids = unique(ids)
+	if len(ids) > s.maxBatch {
+		return nil, nil, fmt.Errorf("%w: at most %d ids per batch, got %d", errdef.ErrInvalidInput, s.maxBatch, len(ids))
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	byID, err := s.cache.GetMany(ctx, ids)
+	if err != nil {
+		s.logger.WarnContext(ctx, "cache batch lookup failed", "ids", len(ids), "error", err)
+		metrics.CacheLookup(metrics.CacheError)
+		byID = make(map[string]models.Order, len(ids))
+	}
+
+	var misses []string
+	for _, id := range ids {
+		if _, ok := byID[id]; ok {
+			metrics.CacheLookup(metrics.CacheHit)
+			continue
+		}
+		if err == nil {
+			metrics.CacheLookup(metrics.CacheMiss)
+		}
+		misses = append(misses, id)
+	}
+	span.SetAttributes(attribute.Int("cache.hits", len(ids)-len(misses)))
+
+	if len(misses) > 0 {
+		loaded, err := s.storage.GetOrdersByIDs(ctx, misses)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, o := range loaded {
+			byID[o.OrderUID] = o
+		}
+
+		//warm the cache with what was loaded, detached from the request like in GetOrder
+		cacheCtx := context.WithoutCancel(ctx)
+		go func() {
+			for _, o := range loaded {
+				if err := s.cache.Set(cacheCtx, o.OrderUID, o); err != nil {
+					s.logger.WarnContext(cacheCtx, "cache set failed", "order_uid", o.OrderUID, "error", err)
+				}
+			}
+		}()
+	}
+
+	found = make([]models.Order, 0, len(ids))
+	for _, id := range ids {
+		if o, ok := byID[id]; ok {
+			found = append(found, o)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing, nil
+}
+
+func unique(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+// ListOrders returns a page of orders, the limit is clamped to [1, MaxListLimit]
+func (s *OrderService) ListOrders(ctx context.Context, filter models.OrderFilter) (orders []models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.ListOrders")
+	defer func() { tracing.End(span, err) }()
+
+	if filter.Limit <= 0 || filter.Limit > MaxListLimit {
+		filter.Limit = MaxListLimit
+	}
+	return s.storage.ListOrders(ctx, filter)
+}
+
+// ExportOrders streams every order matching filter to fn, ordered by order_uid.
+// Exports skip the cache, they would only evict the orders people are looking at.
+func (s *OrderService) ExportOrders(ctx context.Context, filter models.OrderFilter, fn func(models.Order) error) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.ExportOrders")
+	count := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("orders", count))
+		tracing.End(span, err)
+	}()
+
+	return s.storage.StreamOrders(ctx, filter, func(o models.Order) error {
+		count++
+		return fn(o)
+	})
+}
+
 // func (s *OrderService) FillCache(ctx context.Context, limit int) {
 // 	//get orders from the storage
 // 	orders, err := s.storage.GetLastOrders(ctx, limit)
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"order_service/internal/errdef"
+	"order_service/internal/generator"
+	"order_service/internal/logging"
+	"order_service/internal/models"
+	"order_service/internal/ports/adapters/cache"
+	"order_service/internal/ports/adapters/storage"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errInjected = errors.New("injected")
+
+type fixture struct {
+	storage *storage.OrderStorageMemory
+	cache   *cache.OrderCacheMemory
+	service *OrderService
+	gen     *generator.Generator
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	gen, err := generator.New(generator.Options{Seed: 1, MinItems: 1, MaxItems: 3, Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := storage.NewOrderStorageMemory()
+	c := cache.NewOrderCacheMemory(time.Hour)
+	return fixture{storage: st, cache: c, service: NewOrderService(st, c, logging.Nop()), gen: gen}
+}
+
+// saved stores a new order directly, bypassing the service, and returns it as stored
+func (f fixture) saved(t *testing.T) models.Order {
+	t.Helper()
+	o := f.gen.Order()
+	if err := f.storage.SaveOrder(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	o, err := f.storage.GetOrderByID(context.Background(), o.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+// waitCached waits for the background cache fill of GetOrder and GetOrders
+func waitCached(t *testing.T, c *cache.OrderCacheMemory, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Len() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("cache has %d entries, want %d", c.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGetOrderCacheMissFillsCache(t *testing.T) {
+	f := newFixture(t)
+	want := f.saved(t)
+
+	got, err := f.service.GetOrder(context.Background(), want.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	waitCached(t, f.cache, 1)
+}
+
+func TestGetOrderCacheHitSkipsStorage(t *testing.T) {
+	f := newFixture(t)
+	want := f.gen.Order()
+	if err := f.cache.Set(context.Background(), want.OrderUID, want); err != nil {
+		t.Fatal(err)
+	}
+	f.storage.SetFault(storage.FailOn(errInjected))
+
+	got, err := f.service.GetOrder(context.Background(), want.OrderUID)
+	if err != nil {
+		t.Fatalf("a cache hit must not reach the storage: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGetOrderCacheErrorFallsBackToStorage(t *testing.T) {
+	f := newFixture(t)
+	want := f.saved(t)
+	f.cache.SetFault(cache.FailOn(errInjected, cache.OpGet))
+
+	got, err := f.service.GetOrder(context.Background(), want.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OrderUID != want.OrderUID {
+		t.Fatalf("got order %s, want %s", got.OrderUID, want.OrderUID)
+	}
+}
+
+func TestGetOrderExpiredEntryIsAMiss(t *testing.T) {
+	f := newFixture(t)
+	var (
+		mu  sync.Mutex
+		now = time.Now()
+	)
+	f.cache.WithClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	stale := f.saved(t)
+	cached := stale
+	cached.Locale = "stale"
+	if err := f.cache.Set(context.Background(), stale.OrderUID, cached); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	now = now.Add(time.Hour)
+	mu.Unlock()
+
+	got, err := f.service.GetOrder(context.Background(), stale.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Locale == "stale" {
+		t.Fatal("an expired cache entry was served")
+	}
+}
+
+func TestGetOrderNotFound(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.service.GetOrder(context.Background(), "missing")
+	if !errors.Is(err, errdef.ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetOrderStorageError(t *testing.T) {
+	f := newFixture(t)
+	f.storage.SetFault(storage.FailOn(errInjected, storage.OpGet))
+	_, err := f.service.GetOrder(context.Background(), "any")
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("got %v, want the storage error", err)
+	}
+}
+
+func TestGetOrdersMixesCacheAndStorage(t *testing.T) {
+	f := newFixture(t)
+	cached, stored := f.saved(t), f.saved(t)
+	if err := f.cache.Set(context.Background(), cached.OrderUID, cached); err != nil {
+		t.Fatal(err)
+	}
+
+	found, missing, err := f.service.GetOrders(context.Background(), []string{stored.OrderUID, "missing", cached.OrderUID, stored.OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].OrderUID != stored.OrderUID || found[1].OrderUID != cached.OrderUID {
+		t.Fatalf("found %v, want %s then %s", uids(found), stored.OrderUID, cached.OrderUID)
+	}
+	if !reflect.DeepEqual(missing, []string{"missing"}) {
+		t.Fatalf("missing %v, want [missing]", missing)
+	}
+	waitCached(t, f.cache, 2)
+}
+
+func TestGetOrdersCacheErrorFallsBackToStorage(t *testing.T) {
+	f := newFixture(t)
+	o := f.saved(t)
+	f.cache.SetFault(cache.FailOn(errInjected, cache.OpGetMany))
+
+	found, _, err := f.service.GetOrders(context.Background(), []string{o.OrderUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("found %d orders, want 1", len(found))
+	}
+}
+
+func TestGetOrdersRejectsLargeBatches(t *testing.T) {
+	f := newFixture(t)
+	f.service.WithMaxBatch(2)
+	_, _, err := f.service.GetOrders(context.Background(), []string{"a", "b", "c"})
+	if !errors.Is(err, errdef.ErrInvalidInput) {
+		t.Fatalf("got %v, want ErrInvalidInput", err)
+	}
+}
+
+type recordingNotifier struct {
+	saved []string
+}
+
+func (n *recordingNotifier) OrderSaved(_ context.Context, o models.Order) {
+	n.saved = append(n.saved, o.OrderUID)
+}
+
+func TestSaveOrder(t *testing.T) {
+	f := newFixture(t)
+	n := &recordingNotifier{}
+	f.service.WithNotifier(n)
+	o := f.gen.Order()
+
+	if err := f.service.SaveOrder(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.SaveOrder(context.Background(), o); !errors.Is(err, errdef.ErrAlreadyExists) {
+		t.Fatalf("second save: got %v, want ErrAlreadyExists", err)
+	}
+	if !reflect.DeepEqual(n.saved, []string{o.OrderUID}) {
+		t.Fatalf("notified %v, want only the first save", n.saved)
+	}
+}
+
+func TestSaveOrderRejectsInvalid(t *testing.T) {
+	f := newFixture(t)
+	f.service.WithValidation(true)
+	o := f.gen.Order()
+	o.Items = nil
+
+	err := f.service.SaveOrder(context.Background(), o)
+	var verr *errdef.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	if f.storage.Len() != 0 {
+		t.Fatal("an invalid order reached the storage")
+	}
+}
+
+func TestSaveOrderWithoutValidation(t *testing.T) {
+	f := newFixture(t)
+	o := f.gen.Order()
+	o.Items = nil
+
+	if err := f.service.SaveOrder(context.Background(), o); err != nil {
+		t.Fatalf("an order without items was rejected with validation off: %v", err)
+	}
+	if f.storage.Len() != 1 {
+		t.Fatal("the order did not reach the storage")
+	}
+}
+
+func TestSaveOrderStorageError(t *testing.T) {
+	f := newFixture(t)
+	f.storage.SetFault(storage.FailOn(errInjected, storage.OpSave))
+	if err := f.service.SaveOrder(context.Background(), f.gen.Order()); !errors.Is(err, errInjected) {
+		t.Fatalf("got %v, want the storage error", err)
+	}
+}
+
+func TestExportOrders(t *testing.T) {
+	f := newFixture(t)
+	for range 5 {
+		f.saved(t)
+	}
+
+	var got []string
+	err := f.service.ExportOrders(context.Background(), models.OrderFilter{Limit: 3}, func(o models.Order) error {
+		got = append(got, o.OrderUID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("exported %d orders, want 3", len(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i-1] >= got[i] {
+			t.Fatalf("export is not ordered by order_uid: %v", got)
+		}
+	}
+}
+
+func uids(orders []models.Order) []string {
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.OrderUID
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+)
+
+// KafkaHeaderCarrier adapts kafka message headers to the otel propagation carrier
+type KafkaHeaderCarrier struct {
+	Headers *[]kafka.Header
+}
+
+func (c KafkaHeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c KafkaHeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c KafkaHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// ExtractKafka continues the producer's trace from the message headers
+func ExtractKafka(ctx context.Context, msg *kafka.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, KafkaHeaderCarrier{Headers: &msg.Headers})
+}
+
+// InjectKafka writes the current trace context into the message headers
+func InjectKafka(ctx context.Context, msg *kafka.Message) {
+	otel.GetTextMapPropagator().Inject(ctx, KafkaHeaderCarrier{Headers: &msg.Headers})
+}
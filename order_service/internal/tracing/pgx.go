@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxTracer emits a client span for every statement run through a pgx connection,
+// batches get one span with an event per queued statement
+type PgxTracer struct{}
+
+func (PgxTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = Start(ctx, spanName(data.SQL), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBQueryText(data.SQL),
+		semconv.DBNamespace(conn.Config().Database),
+	))
+	return ctx
+}
+
+func (PgxTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	End(span, data.Err)
+}
+
+func (PgxTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = Start(ctx, "postgres batch", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBNamespace(conn.Config().Database),
+		attribute.Int("db.batch.size", data.Batch.Len()),
+	))
+	return ctx
+}
+
+func (PgxTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	attrs := []attribute.KeyValue{semconv.DBQueryText(data.SQL), attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected())}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("batch query", trace.WithAttributes(attrs...))
+}
+
+func (PgxTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	End(trace.SpanFromContext(ctx), data.Err)
+}
+
+// spanName keeps span names low-cardinality: the operation and, for DML, the table
+func spanName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "postgres"
+	}
+	op := strings.ToUpper(fields[0])
+	for i, f := range fields {
+		upper := strings.ToUpper(f)
+		if (upper == "INTO" || upper == "FROM" || upper == "UPDATE") && i+1 < len(fields) {
+			return "postgres " + op + " " + strings.Trim(fields[i+1], "(")
+		}
+	}
+	return "postgres " + op
+}
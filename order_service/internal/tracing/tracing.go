@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"order_service/internal/config"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "order_service"
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// The returned func flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, conf config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closeOutput, err := newExporter(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		//spans are still created so the trace context is propagated, they are just not recorded
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(conf.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("cannot build trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closeOutput != nil {
+			closeOutput.Close()
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, conf config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch conf.Exporter {
+	case "", "none":
+		return nil, nil, nil
+	case "otlp":
+		var opts []otlptracehttp.Option
+		//without an explicit endpoint the exporter follows the OTEL_EXPORTER_OTLP_* env variables
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(conf.Endpoint))
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create otlp exporter: %w", err)
+		}
+		return exp, nil, nil
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create stdout exporter: %w", err)
+		}
+		return exp, nil, nil
+	case "file":
+		f, err := os.OpenFile(conf.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot open trace file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("cannot create file exporter: %w", err)
+		}
+		return exp, f, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q, expected none, otlp, stdout or file", conf.Exporter)
+	}
+}
+
+// Start opens a span with the service tracer, it is a shorthand for the components
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End marks the span failed when err is set and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}